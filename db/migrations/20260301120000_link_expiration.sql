@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE links
+    ADD COLUMN expires_at TIMESTAMP,
+    ADD COLUMN max_visits BIGINT,
+    ADD COLUMN expired BOOLEAN NOT NULL DEFAULT FALSE;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE links
+    DROP COLUMN IF EXISTS expired,
+    DROP COLUMN IF EXISTS max_visits,
+    DROP COLUMN IF EXISTS expires_at;
+-- +goose StatementEnd
@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE links
+    ADD COLUMN password_hash TEXT;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE links
+    DROP COLUMN IF EXISTS password_hash;
+-- +goose StatementEnd
@@ -0,0 +1,19 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE link_visits
+    ADD COLUMN browser VARCHAR(64),
+    ADD COLUMN browser_version VARCHAR(32),
+    ADD COLUMN os VARCHAR(64),
+    ADD COLUMN device_type VARCHAR(16),
+    ADD COLUMN is_bot BOOLEAN NOT NULL DEFAULT FALSE;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE link_visits
+    DROP COLUMN IF EXISTS is_bot,
+    DROP COLUMN IF EXISTS device_type,
+    DROP COLUMN IF EXISTS os,
+    DROP COLUMN IF EXISTS browser_version,
+    DROP COLUMN IF EXISTS browser;
+-- +goose StatementEnd
@@ -0,0 +1,22 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE link_visits
+    ADD COLUMN country VARCHAR(2),
+    ADD COLUMN region VARCHAR(128),
+    ADD COLUMN city VARCHAR(128),
+    ADD COLUMN asn BIGINT,
+    ADD COLUMN as_org VARCHAR(255);
+
+CREATE INDEX idx_link_visits_country ON link_visits(country);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_link_visits_country;
+ALTER TABLE link_visits
+    DROP COLUMN IF EXISTS as_org,
+    DROP COLUMN IF EXISTS asn,
+    DROP COLUMN IF EXISTS city,
+    DROP COLUMN IF EXISTS region,
+    DROP COLUMN IF EXISTS country;
+-- +goose StatementEnd
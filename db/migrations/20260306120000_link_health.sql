@@ -0,0 +1,19 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE link_health (
+    link_id BIGINT PRIMARY KEY REFERENCES links(id) ON DELETE CASCADE,
+    status_code INTEGER,
+    error TEXT,
+    latency_ms INTEGER NOT NULL,
+    broken BOOLEAN NOT NULL,
+    checked_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX idx_link_health_checked_at ON link_health(checked_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_link_health_checked_at;
+DROP TABLE IF EXISTS link_health;
+-- +goose StatementEnd
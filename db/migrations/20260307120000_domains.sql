@@ -0,0 +1,60 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE domains (
+    id BIGSERIAL PRIMARY KEY,
+    host VARCHAR(255) NOT NULL UNIQUE,
+    owner_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_domains_owner_id ON domains(owner_id);
+
+ALTER TABLE links
+    ADD COLUMN domain_id BIGINT REFERENCES domains(id) ON DELETE CASCADE;
+
+-- Short names were never enforced to be unique. Keep the oldest link for
+-- every name and suffix the others with their id so the index can be built.
+-- A suffixed name can itself be taken, so a counter is appended until it
+-- is free.
+DO $$
+DECLARE
+    link RECORD;
+    base TEXT;
+    candidate TEXT;
+    attempt INT;
+BEGIN
+    FOR link IN
+        SELECT id, short_name FROM links
+        WHERE EXISTS (
+            SELECT 1 FROM links older
+            WHERE older.short_name = links.short_name AND older.id < links.id
+        )
+        ORDER BY id
+    LOOP
+        base := link.short_name || '-' || link.id;
+        candidate := base;
+        attempt := 1;
+        WHILE EXISTS (
+            SELECT 1 FROM links
+            WHERE short_name = candidate AND id <> link.id
+        ) LOOP
+            attempt := attempt + 1;
+            candidate := base || '-' || attempt;
+        END LOOP;
+
+        UPDATE links SET short_name = candidate WHERE id = link.id;
+    END LOOP;
+END
+$$;
+
+-- Links without a domain live on BASE_PATH and share one namespace.
+CREATE UNIQUE INDEX idx_links_domain_short_name ON links (COALESCE(domain_id, 0), short_name);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_links_domain_short_name;
+ALTER TABLE links DROP COLUMN IF EXISTS domain_id;
+DROP INDEX IF EXISTS idx_domains_owner_id;
+DROP TABLE IF EXISTS domains;
+-- +goose StatementEnd
@@ -0,0 +1,9 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE SEQUENCE short_code_seq;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP SEQUENCE IF EXISTS short_code_seq;
+-- +goose StatementEnd
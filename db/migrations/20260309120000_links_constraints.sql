@@ -0,0 +1,91 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Links without a destination cannot be redirected anywhere.
+DELETE FROM links WHERE original_url IS NULL;
+
+UPDATE links SET created_at = CURRENT_TIMESTAMP WHERE created_at IS NULL;
+UPDATE links SET updated_at = created_at WHERE updated_at IS NULL;
+
+-- Short names become required and unique regardless of case. Links without
+-- a name get 'link-<id>'; for every other name the oldest link keeps it and
+-- the others are suffixed with their id. A new name can itself be taken, so
+-- a counter is appended until it is free.
+DO $$
+DECLARE
+    link RECORD;
+    base TEXT;
+    candidate TEXT;
+    attempt INT;
+BEGIN
+    FOR link IN
+        SELECT id, short_name, domain_id FROM links
+        WHERE short_name IS NULL OR EXISTS (
+            SELECT 1 FROM links older
+            WHERE LOWER(older.short_name) = LOWER(links.short_name)
+              AND COALESCE(older.domain_id, 0) = COALESCE(links.domain_id, 0)
+              AND older.id < links.id
+        )
+        ORDER BY id
+    LOOP
+        base := COALESCE(link.short_name, 'link') || '-' || link.id;
+        candidate := base;
+        attempt := 1;
+        WHILE EXISTS (
+            SELECT 1 FROM links
+            WHERE LOWER(short_name) = LOWER(candidate)
+              AND COALESCE(domain_id, 0) = COALESCE(link.domain_id, 0)
+              AND id <> link.id
+        ) LOOP
+            attempt := attempt + 1;
+            candidate := base || '-' || attempt;
+        END LOOP;
+
+        UPDATE links SET short_name = candidate WHERE id = link.id;
+    END LOOP;
+END
+$$;
+
+ALTER TABLE links
+    ALTER COLUMN original_url SET NOT NULL,
+    ALTER COLUMN short_name SET NOT NULL,
+    ALTER COLUMN created_at SET NOT NULL,
+    ALTER COLUMN updated_at SET NOT NULL;
+
+DROP INDEX IF EXISTS idx_links_domain_short_name;
+CREATE UNIQUE INDEX idx_links_domain_short_name_lower ON links (COALESCE(domain_id, 0), LOWER(short_name));
+
+CREATE OR REPLACE FUNCTION set_updated_at() RETURNS TRIGGER AS $$
+BEGIN
+    NEW.updated_at = CURRENT_TIMESTAMP;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Only edits count: redirects, the expiration sweep and ownership changes
+-- also update links but leave updated_at alone.
+CREATE TRIGGER links_set_updated_at
+    BEFORE UPDATE ON links
+    FOR EACH ROW
+    WHEN (OLD.original_url IS DISTINCT FROM NEW.original_url
+       OR OLD.short_name IS DISTINCT FROM NEW.short_name
+       OR OLD.expires_at IS DISTINCT FROM NEW.expires_at
+       OR OLD.max_visits IS DISTINCT FROM NEW.max_visits
+       OR OLD.password_hash IS DISTINCT FROM NEW.password_hash
+       OR OLD.domain_id IS DISTINCT FROM NEW.domain_id)
+    EXECUTE FUNCTION set_updated_at();
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TRIGGER IF EXISTS links_set_updated_at ON links;
+DROP FUNCTION IF EXISTS set_updated_at();
+
+DROP INDEX IF EXISTS idx_links_domain_short_name_lower;
+CREATE UNIQUE INDEX idx_links_domain_short_name ON links (COALESCE(domain_id, 0), short_name);
+
+ALTER TABLE links
+    ALTER COLUMN updated_at DROP NOT NULL,
+    ALTER COLUMN created_at DROP NOT NULL,
+    ALTER COLUMN short_name DROP NOT NULL,
+    ALTER COLUMN original_url DROP NOT NULL;
+-- +goose StatementEnd
@@ -0,0 +1,14 @@
+-- +goose NO TRANSACTION
+
+-- +goose Up
+-- +goose StatementBegin
+-- Serves the keyset pagination of the visits list, which walks visits from
+-- the newest with id as tie-breaker. link_visits is the largest table, so
+-- the index is built without blocking the recorder's writes.
+CREATE INDEX CONCURRENTLY idx_link_visits_created_at_id ON link_visits (created_at DESC, id DESC);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX CONCURRENTLY IF EXISTS idx_link_visits_created_at_id;
+-- +goose StatementEnd
@@ -0,0 +1,21 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Counts the redirects of links with max_visits, so that the limit can be
+-- enforced with a single conditional update instead of counting visits
+-- that the recorder has not written yet.
+ALTER TABLE links ADD COLUMN redirects BIGINT NOT NULL DEFAULT 0;
+
+UPDATE links
+    SET redirects = (
+        SELECT COUNT(1) FROM link_visits
+        WHERE link_visits.link_id = links.id
+          AND link_visits.status = 302
+          AND link_visits.is_bot = FALSE
+    )
+WHERE max_visits IS NOT NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE links DROP COLUMN IF EXISTS redirects;
+-- +goose StatementEnd
@@ -0,0 +1,27 @@
+-- +goose NO TRANSACTION
+
+-- +goose Up
+-- +goose StatementBegin
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+-- +goose StatementEnd
+
+-- The q filter of the links list matches substrings of the destination and
+-- the short name with ILIKE, which trigram indexes can serve. Each index is
+-- its own statement, since CREATE INDEX CONCURRENTLY cannot run in the
+-- implicit transaction of a multi-statement query.
+-- +goose StatementBegin
+CREATE INDEX CONCURRENTLY idx_links_original_url_trgm ON links USING GIN (original_url gin_trgm_ops);
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE INDEX CONCURRENTLY idx_links_short_name_trgm ON links USING GIN (short_name gin_trgm_ops);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX CONCURRENTLY IF EXISTS idx_links_short_name_trgm;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+DROP INDEX CONCURRENTLY IF EXISTS idx_links_original_url_trgm;
+-- +goose StatementEnd
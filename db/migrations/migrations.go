@@ -0,0 +1,8 @@
+// Package migrations embeds the goose migrations, so that the running
+// binary can tell whether the database schema is up to date.
+package migrations
+
+import "embed"
+
+//go:embed *.sql
+var FS embed.FS
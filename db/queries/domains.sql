@@ -0,0 +1,30 @@
+-- name: GetDomains :many
+SELECT * FROM domains
+ORDER BY id;
+
+-- name: GetDomainsByOwner :many
+SELECT * FROM domains
+WHERE owner_id = $1
+ORDER BY id;
+
+-- name: GetDomain :one
+SELECT * FROM domains
+WHERE id = $1 AND owner_id = $2;
+
+-- name: CreateDomain :one
+INSERT INTO domains (
+    host, owner_id
+) VALUES (
+    $1, $2
+)
+RETURNING *;
+
+-- name: DeleteDomain :many
+WITH deleted AS (
+    DELETE FROM domains
+    WHERE id = $1 AND owner_id = $2
+    RETURNING id
+)
+SELECT deleted.id, links.short_name
+FROM deleted
+LEFT JOIN links ON links.domain_id = deleted.id;
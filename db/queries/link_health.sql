@@ -0,0 +1,29 @@
+-- name: GetLinksDueForHealthCheck :many
+SELECT links.id, links.original_url
+FROM links
+LEFT JOIN link_health ON link_health.link_id = links.id
+WHERE links.expired = FALSE
+  AND (link_health.checked_at IS NULL OR link_health.checked_at < sqlc.arg(checked_before))
+ORDER BY link_health.checked_at NULLS FIRST, links.id
+LIMIT sqlc.arg('limit');
+
+-- name: GetLinkHealth :one
+SELECT * FROM link_health
+WHERE link_id = $1;
+
+-- name: UpsertLinkHealth :exec
+INSERT INTO link_health (
+    link_id, status_code, error, latency_ms, broken, checked_at
+) VALUES (
+    $1, $2, $3, $4, $5, $6
+)
+ON CONFLICT (link_id) DO UPDATE
+    SET status_code = EXCLUDED.status_code,
+        error = EXCLUDED.error,
+        latency_ms = EXCLUDED.latency_ms,
+        broken = EXCLUDED.broken,
+        checked_at = EXCLUDED.checked_at;
+
+-- name: DeleteLinkHealth :exec
+DELETE FROM link_health
+WHERE link_id = $1;
@@ -1,25 +1,132 @@
 -- name: CreateLinkVisit :one
-INSERT INTO link_visits (link_id, ip, user_agent, referer, status)
-VALUES ($1, $2, $3, $4, $5)
-RETURNING id, link_id, ip, user_agent, referer, status, created_at;
+INSERT INTO link_visits (
+    link_id, ip, user_agent, referer, status,
+    browser, browser_version, os, device_type, is_bot,
+    country, region, city, asn, as_org
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15
+)
+RETURNING id, link_id, ip, user_agent, referer, status, created_at,
+          browser, browser_version, os, device_type, is_bot,
+          country, region, city, asn, as_org;
 
 
--- name: GetAllLinkVisits :many
-SELECT id, link_id, ip, user_agent, referer, status, created_at
+-- name: GetLinkVisitsAfter :many
+SELECT link_visits.id, link_visits.link_id, link_visits.ip, link_visits.user_agent,
+       link_visits.referer, link_visits.status, link_visits.created_at,
+       link_visits.browser, link_visits.browser_version, link_visits.os,
+       link_visits.device_type, link_visits.is_bot,
+       link_visits.country, link_visits.region, link_visits.city,
+       link_visits.asn, link_visits.as_org
 FROM link_visits
-ORDER BY created_at DESC
-LIMIT $1 OFFSET $2;
+JOIN links ON links.id = link_visits.link_id
+WHERE links.owner_id = sqlc.arg(owner_id)
+  AND (sqlc.narg(browser)::text IS NULL OR link_visits.browser = sqlc.narg(browser)::text)
+  AND (sqlc.narg(os)::text IS NULL OR link_visits.os = sqlc.narg(os)::text)
+  AND (sqlc.narg(device_type)::text IS NULL OR link_visits.device_type = sqlc.narg(device_type)::text)
+  AND (sqlc.narg(is_bot)::boolean IS NULL OR link_visits.is_bot = sqlc.narg(is_bot)::boolean)
+  AND (sqlc.narg(country)::text IS NULL OR link_visits.country = sqlc.narg(country)::text)
+  AND (sqlc.narg(link_id)::bigint IS NULL OR link_visits.link_id = sqlc.narg(link_id)::bigint)
+  AND (sqlc.narg(ip)::text IS NULL OR link_visits.ip = sqlc.narg(ip)::text)
+  AND (sqlc.narg(status)::integer IS NULL OR link_visits.status = sqlc.narg(status)::integer)
+  AND (sqlc.narg(created_from)::timestamp IS NULL OR link_visits.created_at >= sqlc.narg(created_from)::timestamp)
+  AND (sqlc.narg(created_to)::timestamp IS NULL OR link_visits.created_at < sqlc.narg(created_to)::timestamp)
+  AND (sqlc.narg(after_created_at)::timestamp IS NULL
+    OR (link_visits.created_at, link_visits.id) < (sqlc.narg(after_created_at)::timestamp, sqlc.arg(after_id)::bigint))
+ORDER BY link_visits.created_at DESC, link_visits.id DESC
+LIMIT sqlc.arg('limit');
+
+-- name: GetLinkVisitsByIDs :many
+SELECT id, link_id, ip, user_agent, referer, status, created_at,
+       browser, browser_version, os, device_type, is_bot,
+       country, region, city, asn, as_org
+FROM link_visits
+WHERE id = ANY(sqlc.arg(ids)::bigint[]);
 
 -- name: CountLinkVisits :one
-SELECT COUNT(1) FROM link_visits;
+SELECT COUNT(1) FROM link_visits
+JOIN links ON links.id = link_visits.link_id
+WHERE links.owner_id = sqlc.arg(owner_id)
+  AND (sqlc.narg(browser)::text IS NULL OR link_visits.browser = sqlc.narg(browser)::text)
+  AND (sqlc.narg(os)::text IS NULL OR link_visits.os = sqlc.narg(os)::text)
+  AND (sqlc.narg(device_type)::text IS NULL OR link_visits.device_type = sqlc.narg(device_type)::text)
+  AND (sqlc.narg(is_bot)::boolean IS NULL OR link_visits.is_bot = sqlc.narg(is_bot)::boolean)
+  AND (sqlc.narg(country)::text IS NULL OR link_visits.country = sqlc.narg(country)::text)
+  AND (sqlc.narg(link_id)::bigint IS NULL OR link_visits.link_id = sqlc.narg(link_id)::bigint)
+  AND (sqlc.narg(ip)::text IS NULL OR link_visits.ip = sqlc.narg(ip)::text)
+  AND (sqlc.narg(status)::integer IS NULL OR link_visits.status = sqlc.narg(status)::integer)
+  AND (sqlc.narg(created_from)::timestamp IS NULL OR link_visits.created_at >= sqlc.narg(created_from)::timestamp)
+  AND (sqlc.narg(created_to)::timestamp IS NULL OR link_visits.created_at < sqlc.narg(created_to)::timestamp);
 
 -- name: GetLinkVisitByID :one
-SELECT id, link_id, ip, user_agent, referer, status, created_at
+SELECT id, link_id, ip, user_agent, referer, status, created_at,
+       browser, browser_version, os, device_type, is_bot,
+       country, region, city, asn, as_org
 FROM link_visits
 WHERE id = $1;
 
 -- name: GetVisitsByLinkID :many
-SELECT id, link_id, ip, user_agent, referer, status, created_at
+SELECT id, link_id, ip, user_agent, referer, status, created_at,
+       browser, browser_version, os, device_type, is_bot,
+       country, region, city, asn, as_org
 FROM link_visits
 WHERE link_id = $1
-ORDER BY created_at DESC;
\ No newline at end of file
+ORDER BY created_at DESC;
+
+-- name: GetLinkVisitsSummary :one
+SELECT COUNT(1) AS visits, COUNT(DISTINCT ip) AS unique_ips
+FROM link_visits
+WHERE link_id = sqlc.arg(link_id)
+  AND status = 302
+  AND is_bot = FALSE
+  AND created_at >= sqlc.arg(from_time)::timestamp
+  AND created_at < sqlc.arg(to_time)::timestamp;
+
+-- name: GetLinkVisitsTimeSeries :many
+SELECT date_trunc(sqlc.arg(bucket_size)::text, created_at)::timestamp AS bucket,
+       COUNT(1) AS visits,
+       COUNT(DISTINCT ip) AS unique_ips
+FROM link_visits
+WHERE link_id = sqlc.arg(link_id)
+  AND status = 302
+  AND is_bot = FALSE
+  AND created_at >= sqlc.arg(from_time)::timestamp
+  AND created_at < sqlc.arg(to_time)::timestamp
+GROUP BY bucket
+ORDER BY bucket;
+
+-- name: GetLinkTopReferers :many
+SELECT COALESCE(NULLIF(referer, ''), '(direct)')::text AS value, COUNT(1) AS visits
+FROM link_visits
+WHERE link_id = sqlc.arg(link_id)
+  AND status = 302
+  AND is_bot = FALSE
+  AND created_at >= sqlc.arg(from_time)::timestamp
+  AND created_at < sqlc.arg(to_time)::timestamp
+GROUP BY value
+ORDER BY visits DESC, value
+LIMIT sqlc.arg(top_limit);
+
+-- name: GetLinkTopUserAgents :many
+SELECT COALESCE(NULLIF(user_agent, ''), '(unknown)')::text AS value, COUNT(1) AS visits
+FROM link_visits
+WHERE link_id = sqlc.arg(link_id)
+  AND status = 302
+  AND is_bot = FALSE
+  AND created_at >= sqlc.arg(from_time)::timestamp
+  AND created_at < sqlc.arg(to_time)::timestamp
+GROUP BY value
+ORDER BY visits DESC, value
+LIMIT sqlc.arg(top_limit);
+
+-- name: GetLinkTopCountries :many
+SELECT COALESCE(country, '')::text AS value, COUNT(1) AS visits
+FROM link_visits
+WHERE link_id = sqlc.arg(link_id)
+  AND status = 302
+  AND is_bot = FALSE
+  AND created_at >= sqlc.arg(from_time)::timestamp
+  AND created_at < sqlc.arg(to_time)::timestamp
+GROUP BY value
+ORDER BY visits DESC, value
+LIMIT sqlc.arg(top_limit);
@@ -1,36 +1,120 @@
--- name: GetLinks :many
+-- name: GetLinksAfter :many
 SELECT * FROM links
+WHERE owner_id = sqlc.arg(owner_id)
+  AND id > sqlc.arg(after_id)
+  AND (sqlc.narg(broken)::boolean IS NULL OR EXISTS (
+    SELECT 1 FROM link_health
+    WHERE link_health.link_id = links.id
+      AND link_health.broken = sqlc.narg(broken)::boolean
+  ))
+  AND (sqlc.narg(q)::text IS NULL
+    OR original_url ILIKE sqlc.narg(q)::text
+    OR short_name ILIKE sqlc.narg(q)::text)
+  AND (sqlc.narg(ids)::bigint[] IS NULL OR id = ANY(sqlc.narg(ids)::bigint[]))
+  AND (sqlc.narg(created_from)::timestamp IS NULL OR created_at >= sqlc.narg(created_from)::timestamp)
+  AND (sqlc.narg(created_to)::timestamp IS NULL OR created_at < sqlc.narg(created_to)::timestamp)
 ORDER BY id
-LIMIT $1
-OFFSET $2;
+LIMIT sqlc.arg('limit');
+
+-- name: GetLinksByIDs :many
+SELECT * FROM links
+WHERE id = ANY(sqlc.arg(ids)::bigint[]);
 
 -- name: GetLinksCount :one
-SELECT COUNT(1) FROM links;
+SELECT COUNT(1) FROM links
+WHERE owner_id = sqlc.arg(owner_id)
+  AND (sqlc.narg(broken)::boolean IS NULL OR EXISTS (
+    SELECT 1 FROM link_health
+    WHERE link_health.link_id = links.id
+      AND link_health.broken = sqlc.narg(broken)::boolean
+  ))
+  AND (sqlc.narg(q)::text IS NULL
+    OR original_url ILIKE sqlc.narg(q)::text
+    OR short_name ILIKE sqlc.narg(q)::text)
+  AND (sqlc.narg(ids)::bigint[] IS NULL OR id = ANY(sqlc.narg(ids)::bigint[]))
+  AND (sqlc.narg(created_from)::timestamp IS NULL OR created_at >= sqlc.narg(created_from)::timestamp)
+  AND (sqlc.narg(created_to)::timestamp IS NULL OR created_at < sqlc.narg(created_to)::timestamp);
 
 -- name: GetLinkByShortName :one
 SELECT * FROM links
-WHERE short_name = $1
-LIMIT 1;
+WHERE LOWER(short_name) = LOWER(sqlc.arg(short_name)::text)
+  AND COALESCE(domain_id, 0) = COALESCE(sqlc.narg(domain_id)::bigint, 0);
 
 -- name: GetLink :one
 SELECT * FROM links
-WHERE id = $1 LIMIT 1;
+WHERE id = $1 AND owner_id = $2 LIMIT 1;
 
 -- name: CreateLink :one
 INSERT INTO links (
-    original_url, short_name
+    original_url, short_name, expires_at, max_visits, password_hash, owner_id, domain_id
 ) VALUES (
-    $1, $2
+    $1, $2, $3, $4, $5, $6, $7
 )
 RETURNING *;
 
 -- name: UpdateLink :one
 UPDATE links
-    SET original_url = $2,
-        short_name = $3
-WHERE id = $1
+    SET original_url = sqlc.arg(original_url),
+        short_name = sqlc.arg(short_name),
+        domain_id = sqlc.narg(domain_id),
+        expires_at = sqlc.narg(expires_at),
+        max_visits = sqlc.narg(max_visits),
+        -- The counter is only kept while a limit is set.
+        redirects = CASE WHEN links.max_visits IS NULL AND sqlc.narg(max_visits)::bigint IS NOT NULL
+            THEN (
+                SELECT COUNT(1) FROM link_visits
+                WHERE link_visits.link_id = links.id
+                  AND link_visits.status = 302
+                  AND link_visits.is_bot = FALSE
+            )
+            ELSE redirects END,
+        expired = FALSE,
+        password_hash = CASE WHEN sqlc.arg(set_password)::boolean
+            THEN sqlc.narg(password_hash)::text
+            ELSE password_hash END
+WHERE id = sqlc.arg(id) AND owner_id = sqlc.arg(owner_id)
 RETURNING *;
 
 -- name: DeleteLink :exec
 DELETE FROM links
-WHERE id = $1;
\ No newline at end of file
+WHERE id = $1 AND owner_id = $2;
+
+-- name: DeleteLinks :many
+DELETE FROM links
+WHERE owner_id = sqlc.arg(owner_id) AND id = ANY(sqlc.arg(ids)::bigint[])
+RETURNING id, short_name, domain_id;
+
+-- name: GetLinksForExport :many
+SELECT links.id, links.original_url, links.short_name, links.created_at,
+       links.expires_at, links.max_visits, links.domain_id,
+       (SELECT COUNT(1) FROM link_visits WHERE link_visits.link_id = links.id) AS visits
+FROM links
+WHERE links.owner_id = $1 AND links.id > $2
+ORDER BY links.id
+LIMIT $3;
+
+-- name: GetExistingShortNames :many
+SELECT short_name FROM links
+WHERE LOWER(short_name) = ANY(sqlc.arg(short_names)::text[])
+  AND COALESCE(domain_id, 0) = COALESCE(sqlc.narg(domain_id)::bigint, 0);
+
+-- name: MarkExpiredLinks :execrows
+UPDATE links
+    SET expired = TRUE
+WHERE expired = FALSE
+  AND (
+    (expires_at IS NOT NULL AND expires_at <= NOW())
+    OR (max_visits IS NOT NULL AND max_visits <= redirects)
+  );
+
+-- name: ClaimRedirect :execrows
+UPDATE links
+    SET redirects = redirects + 1,
+        expired = redirects + 1 >= max_visits
+WHERE id = $1
+  AND max_visits IS NOT NULL
+  AND redirects < max_visits
+  AND expired = FALSE;
+
+-- name: NextShortCodeSequence :one
+SELECT nextval('short_code_seq')::bigint;
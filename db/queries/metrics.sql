@@ -0,0 +1,7 @@
+-- name: CountLinks :one
+SELECT COUNT(1) FROM links;
+
+-- name: EstimateLinkVisits :one
+SELECT GREATEST(reltuples, 0)::bigint AS estimate
+FROM pg_class
+WHERE relname = 'link_visits';
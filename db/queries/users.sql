@@ -0,0 +1,31 @@
+-- name: GetUserByEmail :one
+SELECT * FROM users
+WHERE email = $1;
+
+-- name: CreateUser :one
+INSERT INTO users (email)
+VALUES ($1)
+RETURNING *;
+
+-- name: CreateAPIKey :one
+INSERT INTO api_keys (user_id, key_hash)
+VALUES ($1, $2)
+RETURNING *;
+
+-- name: GetUserByAPIKeyHash :one
+SELECT users.id, users.email, users.created_at, api_keys.id AS api_key_id,
+       (api_keys.last_used_at IS NULL
+         OR api_keys.last_used_at < CURRENT_TIMESTAMP - INTERVAL '1 minute')::boolean AS touch_due
+FROM api_keys
+JOIN users ON users.id = api_keys.user_id
+WHERE api_keys.key_hash = $1 AND api_keys.revoked_at IS NULL;
+
+-- name: TouchAPIKey :exec
+UPDATE api_keys
+    SET last_used_at = CURRENT_TIMESTAMP
+WHERE id = $1;
+
+-- name: AssignUnownedLinks :execrows
+UPDATE links
+    SET owner_id = $1
+WHERE owner_id IS NULL;
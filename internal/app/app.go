@@ -1,35 +1,103 @@
 package app
 
 import (
+	"context"
 	"database/sql"
-	"log"
+	"errors"
+	"fmt"
+	"log/slog"
+	"markoni23/url-shortener/db/migrations"
 	"markoni23/url-shortener/internal/config"
+	"markoni23/url-shortener/internal/geoip"
+	domainHandler "markoni23/url-shortener/internal/handler/domain"
+	healthHandler "markoni23/url-shortener/internal/handler/health"
 	linkHandler "markoni23/url-shortener/internal/handler/link"
 	visitHandler "markoni23/url-shortener/internal/handler/link_visit"
+	"markoni23/url-shortener/internal/metrics"
+	"markoni23/url-shortener/internal/middleware"
+	"markoni23/url-shortener/internal/ratelimit"
+	authService "markoni23/url-shortener/internal/service/auth"
+	domainService "markoni23/url-shortener/internal/service/domain"
+	healthService "markoni23/url-shortener/internal/service/health"
 	linkService "markoni23/url-shortener/internal/service/link"
+	linkHealth "markoni23/url-shortener/internal/service/link_health"
 	visitService "markoni23/url-shortener/internal/service/link_visit"
 	"markoni23/url-shortener/internal/sqlcdb"
+	"markoni23/url-shortener/internal/tracing"
+	"markoni23/url-shortener/internal/urlpolicy"
 	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
 
 	"github.com/getsentry/sentry-go"
 	sentrygin "github.com/getsentry/sentry-go/gin"
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 )
 
-func Run(cfg config.Config, db *sql.DB) error {
-	router := gin.Default()
+// sentryFlushTimeout bounds how long buffered Sentry events are given to
+// be delivered on shutdown.
+const sentryFlushTimeout = 5 * time.Second
+
+// tracingShutdownTimeout bounds how long buffered spans are given to be
+// exported on shutdown.
+const tracingShutdownTimeout = 5 * time.Second
+
+// Run starts the HTTP server and blocks until it fails or a termination
+// signal is received. Background workers have stopped by the time it
+// returns, so the caller can close db and redisClient. redisClient may be
+// nil, in which case caching stays in process.
+func Run(cfg config.Config, db *sql.DB, redisClient *redis.Client) error {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// Background workers outlive ctx until the server has drained.
+	bgCtx, stopBackground := context.WithCancel(context.Background())
+	defer stopBackground()
+	var background sync.WaitGroup
+
+	shutdownTracing, err := tracing.Setup(ctx, cfg.Tracing, os.Stdout)
+	if err != nil {
+		return err
+	}
+
+	if !cfg.IsDevelopmentEnv() {
+		gin.SetMode(gin.ReleaseMode)
+	}
+	router := gin.New()
+	// Lets services use the gin context as a context.Context that carries
+	// the request ID, the trace and the request cancellation.
+	router.ContextWithFallback = true
+	router.Use(otelgin.Middleware(cfg.Tracing.ServiceName, otelgin.WithFilter(tracedRequest)))
+	router.Use(middleware.Logger())
+	if cfg.Metrics.Enabled {
+		router.Use(middleware.Metrics())
+	}
+	// Recovery runs inside Logger and Metrics, so that a request that panics
+	// is still logged and counted, as a 500.
+	router.Use(middleware.Recovery())
+	if len(cfg.Server.TrustedProxies) > 0 {
+		if err := router.SetTrustedProxies(cfg.Server.TrustedProxies); err != nil {
+			return fmt.Errorf("invalid trusted proxies: %w", err)
+		}
+	}
 
 	if cfg.Server.SentryDSN != "" {
 		if err := sentry.Init(sentry.ClientOptions{
 			Dsn: cfg.Server.SentryDSN,
 		}); err != nil {
-			log.Printf("Sentry initialization failed: %v\n", err)
+			slog.Error("sentry initialization failed", "error", err)
 		}
 		router.Use(sentrygin.New(sentrygin.Options{Repanic: true}))
 	} else {
-		log.Println("No SentryDSN")
+		slog.Info("sentry disabled, no SENTRY_DSN")
 	}
+	router.Use(middleware.RequestID())
 
 	corsConfig := cors.DefaultConfig()
 
@@ -39,37 +107,257 @@ func Run(cfg config.Config, db *sql.DB) error {
 	}
 
 	corsConfig.AllowMethods = []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}
-	corsConfig.AllowHeaders = []string{"Origin", "Content-Type", "Accept"}
+	corsConfig.AllowHeaders = []string{
+		"Origin", "Content-Type", "Accept", "Authorization",
+		middleware.RequestIDHeader, "traceparent", "tracestate",
+	}
+	corsConfig.ExposeHeaders = []string{middleware.RequestIDHeader}
 	router.Use(cors.New(corsConfig))
 
 	queries := sqlcdb.New(db)
 
-	linkSvc := linkService.NewService(cfg.Server.BasePath, queries)
-	linkHand := linkHandler.NewHandler(linkSvc)
+	linkCache := linkService.NewLinkCache(cfg.Links.Cache, redisClient)
+	background.Go(func() { linkCache.RunInvalidationListener(bgCtx) })
+
+	domains := domainService.NewRegistry(cfg.Server.BasePath, queries)
+	if err := domains.Reload(ctx); err != nil {
+		return fmt.Errorf("failed to load domains: %w", err)
+	}
+	background.Go(func() { domains.RunRefresher(bgCtx, cfg.Links.DomainRefreshInterval) })
+
+	urlPolicy, err := urlpolicy.New(cfg.Links.PolicyFile, cfg.Server.BasePath)
+	if err != nil {
+		return fmt.Errorf("failed to load url policy: %w", err)
+	}
+	urlPolicy.SetOwnHostLookup(func(ctx context.Context, host string) bool {
+		_, ok, _ := domains.Lookup(ctx, host)
+		return ok
+	})
+
+	shortCodes, err := linkService.NewShortCodeGenerators(cfg.Links.ShortCode, queries.NextShortCodeSequence)
+	if err != nil {
+		return fmt.Errorf("invalid short code configuration: %w", err)
+	}
 
-	visitSvc := visitService.NewService(queries)
-	visitHand := visitHandler.NewHandler(visitSvc, linkSvc)
+	linkSvc := linkService.NewService(cfg.Server.BasePath, cfg.Links.RootRedirects, db, queries, linkCache, urlPolicy, domains, shortCodes)
+	linkHand := linkHandler.NewHandler(linkHandler.NewTracedService(linkSvc))
 
-	apiGroup := router.Group("/api")
+	background.Go(func() { linkSvc.RunExpirationSweeper(bgCtx, cfg.Links.ExpirationSweepInterval) })
+
+	if cfg.HealthCheck.Enabled {
+		checker := linkHealth.NewChecker(queries, cfg.HealthCheck)
+		background.Go(func() { checker.Run(bgCtx) })
+	}
+
+	geoResolver, err := geoip.Open(cfg.GeoIP.DBPath, cfg.GeoIP.ASNDBPath)
+	if err != nil {
+		return fmt.Errorf("failed to open geoip database: %w", err)
+	}
+	defer func() {
+		if err := geoResolver.Close(); err != nil {
+			slog.Error("failed to close geoip database", "error", err)
+		}
+	}()
+
+	recorder := visitService.NewRecorder(db, geoResolver, cfg.Visits)
+	recorder.Start()
+
+	visitSvc := visitService.NewService(db, queries, recorder)
+	visitHand := visitHandler.NewHandler(
+		visitHandler.NewTracedVisitService(visitSvc),
+		visitHandler.NewTracedLinkService(linkSvc),
+	)
+
+	authSvc := authService.NewService(queries)
+
+	domainSvc := domainService.NewService(cfg.Server.BasePath, queries, domains, linkCache)
+	domainHand := domainHandler.NewHandler(domainSvc)
+
+	var limiter ratelimit.Limiter = ratelimit.NewMemoryLimiter()
+	if redisClient != nil {
+		limiter = ratelimit.NewRedisLimiter(redisClient)
+	}
+	rateLimit := func(name string, policy config.RateLimitPolicy) gin.HandlerFunc {
+		if !cfg.RateLimit.Enabled {
+			return func(ctx *gin.Context) { ctx.Next() }
+		}
+		return middleware.RateLimit(limiter, name, ratelimitPolicy(policy))
+	}
+	createLimit := rateLimit("create", cfg.RateLimit.Create)
+	redirectLimit := rateLimit("redirect", cfg.RateLimit.Redirect)
+	// Bulk creation and imports are charged one token per link.
+	createItemsLimit := func(ctx *gin.Context) { ctx.Next() }
+	if cfg.RateLimit.Enabled {
+		createItemsLimit = middleware.RateLimitPerItem(limiter, "create", ratelimitPolicy(cfg.RateLimit.Create))
+	}
+
+	// The per-IP limit comes before Auth so that requests with invalid keys
+	// are limited too and cannot be used to hammer the key lookup.
+	apiGroup := router.Group("/api", rateLimit("api-ip", cfg.RateLimit.API), middleware.Auth(authSvc), rateLimit("api", cfg.RateLimit.API))
 	{
 		linksRoutes := apiGroup.Group("/links")
 		{
 			linksRoutes.GET("/", linkHand.GetLinksList)
-			linksRoutes.POST("/", linkHand.CreateLink)
+			linksRoutes.POST("/", createLimit, linkHand.CreateLink)
+			linksRoutes.POST("/bulk", createItemsLimit, linkHand.CreateLinksBulk)
+			linksRoutes.DELETE("/bulk", linkHand.DeleteLinksBulk)
+			linksRoutes.GET("/export", linkHand.ExportLinks)
+			linksRoutes.POST("/import", createItemsLimit, linkHand.ImportLinks)
 			linksRoutes.GET("/:id", linkHand.GetLink)
 			linksRoutes.PUT("/:id", linkHand.UpdateLink)
 			linksRoutes.DELETE("/:id", linkHand.DeleteLink)
+			linksRoutes.GET("/:id/stats", visitHand.GetLinkStats)
+			linksRoutes.GET("/:id/health", linkHand.GetLinkHealth)
+		}
+		domainsRoutes := apiGroup.Group("/domains")
+		{
+			domainsRoutes.GET("/", domainHand.GetDomains)
+			domainsRoutes.POST("/", domainHand.CreateDomain)
+			domainsRoutes.DELETE("/:id", domainHand.DeleteDomain)
 		}
 		apiGroup.GET("/link_visits", visitHand.GetVisits)
+		apiGroup.GET("/cache/stats", linkHand.GetCacheStats)
 	}
 
-	router.GET("/r/:code", visitHand.VisistLink)
+	router.GET("/r/:code", redirectLimit, visitHand.VisistLink)
+	router.POST("/r/:code", redirectLimit, visitHand.UnlockLink)
+	if cfg.Links.RootRedirects {
+		// Static routes such as /ping and the /api group take precedence
+		// over the root parameter.
+		router.GET("/:code", redirectLimit, visitHand.VisistLink)
+		router.POST("/:code", redirectLimit, visitHand.UnlockLink)
+	}
 
 	router.GET("/ping", func(c *gin.Context) {
 		c.String(http.StatusOK, "pong")
 	})
 
+	// Metrics are served on their own address, which is meant to be
+	// reachable by the scraper only, rather than next to the public routes.
+	var metricsSrv *http.Server
+	if cfg.Metrics.Enabled {
+		metrics.RegisterDBStats(db)
+		metrics.RegisterVisitRecorder(recorder)
+		metrics.RegisterLinkCache(linkCache)
+		businessGauges := metrics.NewBusinessGauges(queries)
+		background.Go(func() { businessGauges.Run(bgCtx, cfg.Metrics.RefreshInterval) })
+
+		mux := http.NewServeMux()
+		mux.Handle("GET /metrics", metrics.Handler())
+		metricsSrv = &http.Server{
+			Addr:              cfg.Metrics.Addr,
+			Handler:           mux,
+			ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
+		}
+	}
+
+	healthSvc, err := healthService.NewService(db, migrations.FS)
+	if err != nil {
+		return fmt.Errorf("failed to read migrations: %w", err)
+	}
+	healthHand := healthHandler.NewHandler(healthSvc)
+	router.GET("/healthz", healthHand.Live)
+	router.GET("/readyz", healthHand.Ready)
+
 	//router.GET("/r/:code", linkVisit)
 
-	return router.Run(":" + cfg.Server.Port)
+	srv := &http.Server{
+		Addr:              ":" + cfg.Server.Port,
+		Handler:           router,
+		ReadTimeout:       cfg.Server.ReadTimeout,
+		ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
+		WriteTimeout:      cfg.Server.WriteTimeout,
+		IdleTimeout:       cfg.Server.IdleTimeout,
+	}
+
+	serveErr := make(chan error, 2)
+	go func() {
+		slog.Info("listening", "addr", srv.Addr)
+		serveErr <- srv.ListenAndServe()
+	}()
+	if metricsSrv != nil {
+		go func() {
+			slog.Info("serving metrics", "addr", metricsSrv.Addr)
+			serveErr <- metricsSrv.ListenAndServe()
+		}()
+	}
+
+	var runErr error
+	select {
+	case err := <-serveErr:
+		if !errors.Is(err, http.ErrServerClosed) {
+			runErr = err
+		}
+	case <-ctx.Done():
+		// A second signal terminates the process right away.
+		stop()
+		healthHand.SetShuttingDown()
+		if cfg.Server.DrainPeriod > 0 {
+			slog.Info("shutting down", "drain_period", cfg.Server.DrainPeriod.String())
+			select {
+			case <-time.After(cfg.Server.DrainPeriod):
+			case err := <-serveErr:
+				if !errors.Is(err, http.ErrServerClosed) {
+					runErr = err
+				}
+			}
+		} else {
+			slog.Info("shutting down")
+		}
+	}
+
+	// Every stage gets its own deadline, so a slow one does not leave the
+	// next without time.
+	withTimeout := func(timeout time.Duration, fn func(ctx context.Context) error) error {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		return fn(ctx)
+	}
+
+	if err := withTimeout(cfg.Server.ShutdownTimeout, srv.Shutdown); err != nil {
+		slog.Error("http server shutdown failed", "error", err)
+	}
+	// The recorder is drained after the server stopped accepting requests.
+	// Handlers still running past the shutdown timeout have their visits
+	// dropped. Close waits for the workers, so GeoIP and the database are
+	// only closed once nothing writes visits anymore.
+	if err := withTimeout(cfg.Visits.DrainTimeout, recorder.Close); err != nil {
+		slog.Error("visit recorder shutdown failed", "error", err)
+	}
+
+	if metricsSrv != nil {
+		if err := withTimeout(cfg.Server.ShutdownTimeout, metricsSrv.Shutdown); err != nil {
+			slog.Error("metrics server shutdown failed", "error", err)
+		}
+	}
+
+	stopBackground()
+	background.Wait()
+
+	if err := withTimeout(tracingShutdownTimeout, shutdownTracing); err != nil {
+		slog.Error("tracing shutdown failed", "error", err)
+	}
+
+	if cfg.Server.SentryDSN != "" && !sentry.Flush(sentryFlushTimeout) {
+		slog.Warn("sentry flush timed out")
+	}
+
+	return runErr
+}
+
+// tracedRequest leaves probes out of the traces.
+func tracedRequest(r *http.Request) bool {
+	switch r.URL.Path {
+	case "/healthz", "/readyz":
+		return false
+	default:
+		return true
+	}
+}
+
+func ratelimitPolicy(policy config.RateLimitPolicy) ratelimit.Policy {
+	return ratelimit.Policy{
+		Rate:  float64(policy.PerMinute) / 60,
+		Burst: policy.Burst,
+	}
 }
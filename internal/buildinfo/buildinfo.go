@@ -0,0 +1,31 @@
+// Package buildinfo reports the version of the running binary. Version and
+// Commit can be set at build time with
+//
+//	-ldflags "-X markoni23/url-shortener/internal/buildinfo.Version=v1.2.3
+//	          -X markoni23/url-shortener/internal/buildinfo.Commit=abc123"
+//
+// Otherwise the commit is taken from the VCS information Go embeds.
+package buildinfo
+
+import "runtime/debug"
+
+var (
+	Version = "dev"
+	Commit  = ""
+)
+
+func init() {
+	if Commit != "" {
+		return
+	}
+
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return
+	}
+	for _, setting := range info.Settings {
+		if setting.Key == "vcs.revision" {
+			Commit = setting.Value
+		}
+	}
+}
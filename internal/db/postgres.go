@@ -4,11 +4,13 @@ import (
 	"database/sql"
 	"time"
 
+	"markoni23/url-shortener/internal/tracing"
+
 	_ "github.com/lib/pq"
 )
 
 func InitDB(databaseUrl string) (*sql.DB, error) {
-	db, err := sql.Open("postgres", databaseUrl)
+	db, err := tracing.Open("postgres", databaseUrl)
 	if err != nil {
 		return nil, err
 	}
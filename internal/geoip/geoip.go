@@ -0,0 +1,124 @@
+package geoip
+
+import (
+	"errors"
+	"net"
+
+	"github.com/oschwald/maxminddb-golang"
+)
+
+// Location is the geographical information known for an IP address. Fields
+// missing from the database are left empty.
+type Location struct {
+	Country string
+	Region  string
+	City    string
+	ASN     int64
+	ASOrg   string
+}
+
+// Resolver annotates IP addresses with their location.
+type Resolver interface {
+	Lookup(ip net.IP) (Location, error)
+	Close() error
+}
+
+// record matches the layout shared by the GeoIP2/GeoLite2 City and ASN
+// databases, so the same struct works for either file.
+type record struct {
+	Country struct {
+		IsoCode string `maxminddb:"iso_code"`
+	} `maxminddb:"country"`
+	Subdivisions []struct {
+		IsoCode string            `maxminddb:"iso_code"`
+		Names   map[string]string `maxminddb:"names"`
+	} `maxminddb:"subdivisions"`
+	City struct {
+		Names map[string]string `maxminddb:"names"`
+	} `maxminddb:"city"`
+	AutonomousSystemNumber       uint   `maxminddb:"autonomous_system_number"`
+	AutonomousSystemOrganization string `maxminddb:"autonomous_system_organization"`
+}
+
+type mmdbResolver struct {
+	readers []*maxminddb.Reader
+}
+
+// Open loads the given mmdb files from disk. Typically this is a City
+// database and, optionally, a separate ASN database; data from later files
+// only fills fields the earlier ones left empty. Empty paths are skipped and
+// NopResolver is returned when there is nothing to load.
+func Open(paths ...string) (Resolver, error) {
+	var readers []*maxminddb.Reader
+	for _, path := range paths {
+		if path == "" {
+			continue
+		}
+
+		reader, err := maxminddb.Open(path)
+		if err != nil {
+			for _, r := range readers {
+				_ = r.Close()
+			}
+			return nil, err
+		}
+		readers = append(readers, reader)
+	}
+
+	if len(readers) == 0 {
+		return NopResolver{}, nil
+	}
+	return &mmdbResolver{readers: readers}, nil
+}
+
+func (m *mmdbResolver) Lookup(ip net.IP) (Location, error) {
+	var loc Location
+	if ip == nil {
+		return loc, nil
+	}
+
+	for _, reader := range m.readers {
+		var rec record
+		if err := reader.Lookup(ip, &rec); err != nil {
+			return Location{}, err
+		}
+
+		if loc.Country == "" {
+			loc.Country = rec.Country.IsoCode
+		}
+		if loc.Region == "" && len(rec.Subdivisions) > 0 {
+			loc.Region = rec.Subdivisions[0].Names["en"]
+			if loc.Region == "" {
+				loc.Region = rec.Subdivisions[0].IsoCode
+			}
+		}
+		if loc.City == "" {
+			loc.City = rec.City.Names["en"]
+		}
+		if loc.ASN == 0 {
+			loc.ASN = int64(rec.AutonomousSystemNumber)
+			loc.ASOrg = rec.AutonomousSystemOrganization
+		}
+	}
+
+	return loc, nil
+}
+
+func (m *mmdbResolver) Close() error {
+	var errs []error
+	for _, reader := range m.readers {
+		errs = append(errs, reader.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// NopResolver is used when no database is configured and resolves nothing.
+type NopResolver struct{}
+
+func (NopResolver) Lookup(net.IP) (Location, error) {
+	return Location{}, nil
+}
+
+func (NopResolver) Close() error {
+	return nil
+}
@@ -0,0 +1,259 @@
+package geoip
+
+import (
+	"encoding/binary"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestOpenWithoutPaths(t *testing.T) {
+	resolver, err := Open("", "")
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if _, ok := resolver.(NopResolver); !ok {
+		t.Fatalf("Open without paths returned %T, want NopResolver", resolver)
+	}
+}
+
+func TestOpenMissingFile(t *testing.T) {
+	if _, err := Open(filepath.Join(t.TempDir(), "missing.mmdb")); err == nil {
+		t.Fatal("Open of a missing file succeeded")
+	}
+}
+
+func TestLookup(t *testing.T) {
+	dir := t.TempDir()
+	cityPath := writeFixture(t, dir, "city.mmdb", "GeoIP2-City", map[string]map[string]any{
+		"81.2.69.0/24": {
+			"country":      map[string]any{"iso_code": "GB"},
+			"subdivisions": []any{map[string]any{"iso_code": "ENG", "names": map[string]any{"en": "England"}}},
+			"city":         map[string]any{"names": map[string]any{"en": "London"}},
+		},
+		"89.160.20.0/24": {
+			"country":      map[string]any{"iso_code": "SE"},
+			"subdivisions": []any{map[string]any{"iso_code": "E"}},
+		},
+	})
+	asnPath := writeFixture(t, dir, "asn.mmdb", "GeoLite2-ASN", map[string]map[string]any{
+		"81.2.69.0/23": {
+			"autonomous_system_number":       uint32(20712),
+			"autonomous_system_organization": "Andrews & Arnold Ltd",
+		},
+	})
+
+	resolver, err := Open(cityPath, asnPath)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := resolver.Close(); err != nil {
+			t.Errorf("Close: %v", err)
+		}
+	})
+
+	tests := []struct {
+		name string
+		ip   net.IP
+		want Location
+	}{
+		{
+			name: "city and asn",
+			ip:   net.ParseIP("81.2.69.160"),
+			want: Location{Country: "GB", Region: "England", City: "London", ASN: 20712, ASOrg: "Andrews & Arnold Ltd"},
+		},
+		{
+			name: "asn only",
+			ip:   net.ParseIP("81.2.68.1"),
+			want: Location{ASN: 20712, ASOrg: "Andrews & Arnold Ltd"},
+		},
+		{
+			name: "region falls back to iso code",
+			ip:   net.ParseIP("89.160.20.112"),
+			want: Location{Country: "SE", Region: "E"},
+		},
+		{
+			name: "unknown address",
+			ip:   net.ParseIP("10.0.0.1"),
+			want: Location{},
+		},
+		{
+			name: "nil address",
+			ip:   nil,
+			want: Location{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolver.Lookup(tt.ip)
+			if err != nil {
+				t.Fatalf("Lookup(%v): %v", tt.ip, err)
+			}
+			if got != tt.want {
+				t.Errorf("Lookup(%v) = %+v, want %+v", tt.ip, got, tt.want)
+			}
+		})
+	}
+}
+
+// writeFixture writes an IPv4 MaxMind DB with the given networks and
+// returns its path. It implements just enough of the format for the
+// reader: a search tree with 24 bit records, the data section and the
+// metadata.
+func writeFixture(t *testing.T, dir, name, dbType string, networks map[string]map[string]any) string {
+	t.Helper()
+
+	type record struct {
+		next int // index of the child node, 0 when there is none
+		data int // offset in the data section, -1 when there is none
+	}
+	nodes := [][2]record{{{data: -1}, {data: -1}}}
+
+	var data []byte
+	for cidr, value := range networks {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			t.Fatalf("invalid network %q: %v", cidr, err)
+		}
+		offset := len(data)
+		data = append(data, encodeValue(t, value)...)
+
+		ip := network.IP.To4()
+		ones, _ := network.Mask.Size()
+		current := 0
+		for i := range ones {
+			bit := ip[i/8] >> (7 - i%8) & 1
+			if i == ones-1 {
+				nodes[current][bit].data = offset
+				break
+			}
+			if nodes[current][bit].next == 0 {
+				nodes = append(nodes, [2]record{{data: -1}, {data: -1}})
+				nodes[current][bit].next = len(nodes) - 1
+			}
+			current = nodes[current][bit].next
+		}
+	}
+
+	nodeCount := len(nodes)
+	var file []byte
+	for _, node := range nodes {
+		for _, rec := range node {
+			value := nodeCount
+			switch {
+			case rec.next != 0:
+				value = rec.next
+			case rec.data >= 0:
+				value = nodeCount + 16 + rec.data
+			}
+			file = append(file, byte(value>>16), byte(value>>8), byte(value))
+		}
+	}
+	file = append(file, make([]byte, 16)...)
+	file = append(file, data...)
+	file = append(file, "\xAB\xCD\xEFMaxMind.com"...)
+	file = append(file, encodeValue(t, map[string]any{
+		"binary_format_major_version": uint16(2),
+		"binary_format_minor_version": uint16(0),
+		"build_epoch":                 uint64(1700000000),
+		"database_type":               dbType,
+		"description":                 map[string]any{"en": "Test database"},
+		"ip_version":                  uint16(4),
+		"languages":                   []any{"en"},
+		"node_count":                  uint32(nodeCount),
+		"record_size":                 uint16(24),
+	})...)
+
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, file, 0o600); err != nil {
+		t.Fatalf("write fixture: %v", err)
+	}
+	return path
+}
+
+// MaxMind DB data types.
+const (
+	typeString = 2
+	typeUint16 = 5
+	typeUint32 = 6
+	typeMap    = 7
+	typeUint64 = 9
+	typeArray  = 11
+)
+
+func encodeValue(t *testing.T, value any) []byte {
+	t.Helper()
+
+	switch v := value.(type) {
+	case string:
+		return append(encodeControl(typeString, len(v)), v...)
+	case uint16:
+		return encodeUint(typeUint16, uint64(v))
+	case uint32:
+		return encodeUint(typeUint32, uint64(v))
+	case uint64:
+		return encodeUint(typeUint64, v)
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		slices.Sort(keys)
+
+		out := encodeControl(typeMap, len(v))
+		for _, key := range keys {
+			out = append(out, encodeValue(t, key)...)
+			out = append(out, encodeValue(t, v[key])...)
+		}
+		return out
+	case []any:
+		out := encodeControl(typeArray, len(v))
+		for _, item := range v {
+			out = append(out, encodeValue(t, item)...)
+		}
+		return out
+	default:
+		t.Fatalf("cannot encode %T", value)
+		return nil
+	}
+}
+
+func encodeUint(typ int, v uint64) []byte {
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], v)
+	payload := buf[:]
+	for len(payload) > 0 && payload[0] == 0 {
+		payload = payload[1:]
+	}
+	return append(encodeControl(typ, len(payload)), payload...)
+}
+
+// encodeControl returns the control byte of a value followed by the
+// extended type and size bytes when needed.
+func encodeControl(typ, size int) []byte {
+	var first byte
+	if typ <= 7 {
+		first = byte(typ << 5)
+	}
+
+	var sizeBytes []byte
+	switch {
+	case size < 29:
+		first |= byte(size)
+	case size < 285:
+		first |= 29
+		sizeBytes = []byte{byte(size - 29)}
+	default:
+		first |= 30
+		sizeBytes = []byte{byte((size - 285) >> 8), byte(size - 285)}
+	}
+
+	out := []byte{first}
+	if typ > 7 {
+		out = append(out, byte(typ-7))
+	}
+	return append(out, sizeBytes...)
+}
@@ -0,0 +1,105 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"markoni23/url-shortener/internal/middleware"
+	"markoni23/url-shortener/internal/model"
+	"markoni23/url-shortener/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+)
+
+type Service interface {
+	GetAll(ctx context.Context, ownerID int64) ([]model.Domain, error)
+	Create(ctx context.Context, ownerID int64, host string) (model.Domain, error)
+	Delete(ctx context.Context, ownerID, id int64) error
+}
+
+type handler struct {
+	service Service
+}
+
+func NewHandler(service Service) *handler {
+	return &handler{
+		service: service,
+	}
+}
+
+func (h *handler) GetDomains(ctx *gin.Context) {
+	domains, err := h.service.GetAll(ctx, middleware.CurrentUser(ctx).ID)
+	if err != nil {
+		utils.AbortWithError(ctx, http.StatusInternalServerError, "Failed to get domains", err)
+		return
+	}
+
+	ctx.Header("Content-Range", fmt.Sprintf("domains 0-%d/%d", max(len(domains)-1, 0), len(domains)))
+	ctx.JSON(http.StatusOK, domains)
+}
+
+type CreateDomainRequest struct {
+	Host string `json:"host" binding:"required,fqdn,max=255"`
+}
+
+func (h *handler) CreateDomain(ctx *gin.Context) {
+	var r CreateDomainRequest
+	if err := ctx.ShouldBindJSON(&r); err != nil {
+		if _, ok := err.(validator.ValidationErrors); ok {
+			ctx.JSON(http.StatusUnprocessableEntity, utils.ErrorResponse{
+				Errors: utils.FormatValidationErrors(err),
+			})
+			return
+		}
+
+		ctx.JSON(http.StatusBadRequest, utils.SimpleErrorResponse{
+			Error: "invalid request",
+		})
+		return
+	}
+
+	domain, err := h.service.Create(ctx, middleware.CurrentUser(ctx).ID, r.Host)
+	if err != nil {
+		if errors.Is(err, &model.DomainReservedError{}) {
+			ctx.JSON(http.StatusUnprocessableEntity, utils.ErrorResponse{
+				Errors: map[string]string{"host": err.Error()},
+			})
+			return
+		}
+
+		if utils.IsDuplicateKeyError(err) {
+			ctx.JSON(http.StatusUnprocessableEntity, utils.ErrorResponse{
+				Errors: map[string]string{"host": "host already in use"},
+			})
+			return
+		}
+
+		utils.AbortWithError(ctx, http.StatusBadRequest, "Failed to create domain", err)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, domain)
+}
+
+func (h *handler) DeleteDomain(ctx *gin.Context) {
+	id, err := strconv.ParseInt(ctx.Param("id"), 0, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	if err := h.service.Delete(ctx, middleware.CurrentUser(ctx).ID, id); err != nil {
+		if errors.Is(err, &model.DomainNotFoundError{}) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Domain not found"})
+			return
+		}
+
+		utils.AbortWithError(ctx, http.StatusInternalServerError, "Failed to delete domain", err)
+		return
+	}
+	ctx.Status(http.StatusNoContent)
+}
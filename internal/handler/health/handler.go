@@ -0,0 +1,74 @@
+package health
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+
+	"markoni23/url-shortener/internal/buildinfo"
+	"markoni23/url-shortener/internal/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Service interface {
+	Checks(ctx context.Context) map[string]model.HealthCheck
+}
+
+type handler struct {
+	service      Service
+	shuttingDown atomic.Bool
+}
+
+func NewHandler(service Service) *handler {
+	return &handler{
+		service: service,
+	}
+}
+
+// SetShuttingDown makes the readiness probe fail, so that load balancers
+// stop routing new requests before the server stops accepting them.
+func (h *handler) SetShuttingDown() {
+	h.shuttingDown.Store(true)
+}
+
+// Live reports that the process is up. It does not check dependencies, so
+// that an unreachable database does not get the replica restarted.
+func (h *handler) Live(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, newReport(model.CheckStatusOK, nil))
+}
+
+// Ready reports whether the replica can serve traffic: it is not shutting
+// down, the database is reachable and its schema is up to date.
+func (h *handler) Ready(ctx *gin.Context) {
+	if h.shuttingDown.Load() {
+		report := newReport(model.CheckStatusFail, map[string]model.HealthCheck{
+			"shutdown": {Status: model.CheckStatusFail, Error: "shutting down"},
+		})
+		ctx.JSON(http.StatusServiceUnavailable, report)
+		return
+	}
+
+	checks := h.service.Checks(ctx)
+	status := model.CheckStatusOK
+	for _, check := range checks {
+		if check.Status != model.CheckStatusOK {
+			status = model.CheckStatusFail
+		}
+	}
+
+	code := http.StatusOK
+	if status != model.CheckStatusOK {
+		code = http.StatusServiceUnavailable
+	}
+	ctx.JSON(code, newReport(status, checks))
+}
+
+func newReport(status string, checks map[string]model.HealthCheck) model.HealthReport {
+	return model.HealthReport{
+		Status:  status,
+		Version: buildinfo.Version,
+		Commit:  buildinfo.Commit,
+		Checks:  checks,
+	}
+}
@@ -0,0 +1,123 @@
+package link
+
+import (
+	"errors"
+	"net/http"
+
+	"markoni23/url-shortener/internal/middleware"
+	"markoni23/url-shortener/internal/model"
+	"markoni23/url-shortener/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// Bulk requests are capped at 500 items to bound the transaction size.
+type BulkCreateLinksRequest struct {
+	Links []CreateLinkRequest `json:"links" binding:"required,min=1,max=500"`
+}
+
+type BulkCreateLinksResponse struct {
+	Results []model.BulkItemResult `json:"results"`
+}
+
+type BulkDeleteLinksRequest struct {
+	Ids []int64 `json:"ids" binding:"required,min=1,max=500"`
+}
+
+type BulkDeleteLinksResponse struct {
+	Deleted  []int64 `json:"deleted"`
+	NotFound []int64 `json:"not_found"`
+}
+
+func (h *handler) CreateLinksBulk(ctx *gin.Context) {
+	var r BulkCreateLinksRequest
+	if err := ctx.ShouldBindJSON(&r); err != nil {
+		if _, ok := err.(validator.ValidationErrors); ok {
+			ctx.JSON(http.StatusUnprocessableEntity, utils.ErrorResponse{
+				Errors: utils.FormatValidationErrors(err),
+			})
+			return
+		}
+
+		ctx.JSON(http.StatusBadRequest, utils.SimpleErrorResponse{
+			Error: "invalid request",
+		})
+		return
+	}
+
+	results := make([]model.BulkItemResult, len(r.Links))
+	inputs := make([]model.LinkInput, len(r.Links))
+	invalid := false
+	for i, item := range r.Links {
+		results[i].Index = i
+		if err := binding.Validator.ValidateStruct(item); err != nil {
+			results[i].Errors = utils.FormatValidationErrors(err)
+			invalid = true
+			continue
+		}
+		inputs[i] = item.toInput()
+	}
+
+	if invalid {
+		ctx.JSON(http.StatusUnprocessableEntity, BulkCreateLinksResponse{Results: results})
+		return
+	}
+
+	if !middleware.ChargeRateLimit(ctx, len(inputs)) {
+		return
+	}
+
+	results, err := h.service.CreateBulk(ctx, middleware.CurrentUser(ctx).ID, inputs)
+	if err != nil {
+		if errors.Is(err, &model.BulkRejectedError{}) {
+			ctx.JSON(http.StatusUnprocessableEntity, BulkCreateLinksResponse{Results: results})
+			return
+		}
+
+		utils.AbortWithError(ctx, http.StatusInternalServerError, "Failed to create links", err)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, BulkCreateLinksResponse{Results: results})
+}
+
+func (h *handler) DeleteLinksBulk(ctx *gin.Context) {
+	var r BulkDeleteLinksRequest
+	if err := ctx.ShouldBindJSON(&r); err != nil {
+		if _, ok := err.(validator.ValidationErrors); ok {
+			ctx.JSON(http.StatusUnprocessableEntity, utils.ErrorResponse{
+				Errors: utils.FormatValidationErrors(err),
+			})
+			return
+		}
+
+		ctx.JSON(http.StatusBadRequest, utils.SimpleErrorResponse{
+			Error: "invalid request",
+		})
+		return
+	}
+
+	deleted, err := h.service.DeleteBulk(ctx, middleware.CurrentUser(ctx).ID, r.Ids)
+	if err != nil {
+		utils.AbortWithError(ctx, http.StatusInternalServerError, "Failed to delete links", err)
+		return
+	}
+
+	deletedSet := make(map[int64]struct{}, len(deleted))
+	for _, id := range deleted {
+		deletedSet[id] = struct{}{}
+	}
+	notFound := []int64{}
+	for _, id := range r.Ids {
+		if _, ok := deletedSet[id]; !ok {
+			notFound = append(notFound, id)
+		}
+	}
+
+	ctx.JSON(http.StatusOK, BulkDeleteLinksResponse{
+		Deleted:  deleted,
+		NotFound: notFound,
+	})
+}
@@ -7,21 +7,49 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
+	"markoni23/url-shortener/internal/cache"
+	"markoni23/url-shortener/internal/middleware"
 	"markoni23/url-shortener/internal/model"
+	"markoni23/url-shortener/internal/pagination"
 	"markoni23/url-shortener/internal/utils"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
 	"github.com/go-playground/validator/v10"
 )
 
 type Service interface {
-	Count(ctx context.Context) (int64, error)
-	GetAll(ctx context.Context, from, to int64) ([]model.Link, error)
-	Get(ctx context.Context, id int64) (model.Link, error)
-	Create(ctx context.Context, originalURL, shortName string) (model.Link, error)
-	Update(ctx context.Context, id int64, originalURL, shortName string) (model.Link, error)
-	Delete(ctx context.Context, id int64) error
+	Count(ctx context.Context, ownerID int64, filter model.LinkFilter) (int64, error)
+	GetAll(ctx context.Context, ownerID, from, to int64, filter model.LinkFilter, sort pagination.Sort) ([]model.Link, error)
+	GetPage(ctx context.Context, ownerID int64, page pagination.Params, filter model.LinkFilter) ([]model.Link, *pagination.Cursor, error)
+	Get(ctx context.Context, ownerID, id int64) (model.Link, error)
+	Create(ctx context.Context, ownerID int64, input model.LinkInput) (model.Link, error)
+	Update(ctx context.Context, ownerID, id int64, input model.LinkInput) (model.Link, error)
+	Delete(ctx context.Context, ownerID, id int64) error
+	CreateBulk(ctx context.Context, ownerID int64, inputs []model.LinkInput) ([]model.BulkItemResult, error)
+	ValidateBulk(ctx context.Context, ownerID int64, inputs []model.LinkInput) ([]model.BulkItemResult, error)
+	DeleteBulk(ctx context.Context, ownerID int64, ids []int64) ([]int64, error)
+	Export(ctx context.Context, ownerID int64, fn func(model.LinkExport) error) error
+	ExistingShortNames(ctx context.Context, domainID *int64, shortNames []string) (map[string]bool, error)
+	Health(ctx context.Context, ownerID, id int64) (model.LinkHealth, error)
+	CacheStats() cache.Stats
+}
+
+const maxPasswordBytes = 72
+
+func init() {
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		_ = v.RegisterValidation("notreserved", func(fl validator.FieldLevel) bool {
+			return !model.IsReservedShortName(fl.Field().String())
+		})
+		// bcrypt only uses the first 72 bytes of a password, while max
+		// counts characters.
+		_ = v.RegisterValidation("bcryptlen", func(fl validator.FieldLevel) bool {
+			return len(fl.Field().String()) <= maxPasswordBytes
+		})
+	}
 }
 
 type handler struct {
@@ -34,7 +62,87 @@ func NewHandler(service Service) *handler {
 	}
 }
 
+// linkSortFields are the fields the links list can be sorted by.
+var linkSortFields = []string{"id", "original_url", "short_name", "created_at", "updated_at", "expires_at", "max_visits"}
+
+// linkListFilter is the react-admin filter parameter of the links list. id
+// is sent by react-admin to fetch the links referenced by other records.
+type linkListFilter struct {
+	Q            string  `json:"q"`
+	ID           []int64 `json:"id"`
+	Health       string  `json:"health"`
+	CreatedAtGte string  `json:"created_at_gte"`
+	CreatedAtLte string  `json:"created_at_lte"`
+}
+
+// parseLinkFilter reads the filter parameter and the health query
+// parameter.
+func parseLinkFilter(ctx *gin.Context) (model.LinkFilter, error) {
+	var raw linkListFilter
+	if err := pagination.ParseFilter(ctx, &raw); err != nil {
+		return model.LinkFilter{}, err
+	}
+
+	filter := model.LinkFilter{
+		Query: strings.TrimSpace(raw.Q),
+		IDs:   raw.ID,
+	}
+
+	health := raw.Health
+	if v, ok := ctx.GetQuery("health"); ok {
+		health = v
+	}
+	switch health {
+	case "":
+	case model.HealthStatusBroken, model.HealthStatusOK:
+		broken := health == model.HealthStatusBroken
+		filter.Broken = &broken
+	default:
+		return model.LinkFilter{}, errors.New("invalid 'health' value")
+	}
+
+	var err error
+	if filter.CreatedFrom, err = pagination.ParseDate(raw.CreatedAtGte, false); err != nil {
+		return model.LinkFilter{}, err
+	}
+	if filter.CreatedTo, err = pagination.ParseDate(raw.CreatedAtLte, true); err != nil {
+		return model.LinkFilter{}, err
+	}
+	return filter, nil
+}
+
+// GetLinksList lists the links of the user. With ?after= or ?limit= it
+// pages by cursor, otherwise it serves the react-admin range format.
 func (l *handler) GetLinksList(ctx *gin.Context) {
+	user := middleware.CurrentUser(ctx)
+
+	filter, err := parseLinkFilter(ctx)
+	if err != nil {
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	page, paged, err := pagination.FromQuery(ctx)
+	if err != nil {
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if paged {
+		// Cursors encode the position in id order only.
+		if _, ok := ctx.GetQuery("sort"); ok {
+			ctx.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "sort is not supported with cursor pagination"})
+			return
+		}
+
+		res, next, err := l.service.GetPage(ctx, user.ID, page, filter)
+		if err != nil {
+			utils.AbortWithError(ctx, http.StatusInternalServerError, "Failed to list links", err)
+			return
+		}
+		pagination.Respond(ctx, res, next, page.Limit)
+		return
+	}
+
 	rangeString := ctx.DefaultQuery("range", "[0,10]")
 
 	rangeWithoutBrackets := strings.Trim(rangeString, "[]")
@@ -57,15 +165,27 @@ func (l *handler) GetLinksList(ctx *gin.Context) {
 		return
 	}
 
-	res, err := l.service.GetAll(ctx, int64(from), int64(to))
+	sort, err := pagination.ParseSort(ctx, linkSortFields, pagination.Sort{Field: "id"})
 	if err != nil {
-		ctx.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	count, err := l.service.Count(ctx)
+	res, err := l.service.GetAll(ctx, user.ID, int64(from), int64(to), filter, sort)
 	if err != nil {
-		ctx.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		var invalid *model.InvalidParamsError
+		if errors.As(err, &invalid) {
+			ctx.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": invalid.Reason})
+			return
+		}
+
+		utils.AbortWithError(ctx, http.StatusInternalServerError, "Failed to list links", err)
+		return
+	}
+
+	count, err := l.service.Count(ctx, user.ID, filter)
+	if err != nil {
+		utils.AbortWithError(ctx, http.StatusInternalServerError, "Failed to count links", err)
 		return
 	}
 
@@ -76,8 +196,29 @@ func (l *handler) GetLinksList(ctx *gin.Context) {
 }
 
 type CreateLinkRequest struct {
-	OriginalUrl string `json:"original_url" binding:"required,url"`
-	ShortName   string `json:"short_name" binding:"omitempty,min=3,max=32"`
+	OriginalUrl string     `json:"original_url" binding:"required,url"`
+	ShortName   string     `json:"short_name" binding:"omitempty,min=3,max=32,notreserved"`
+	DomainID    *int64     `json:"domain_id"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+	MaxVisits   *int64     `json:"max_visits" binding:"omitempty,min=1"`
+	Password    string     `json:"password" binding:"omitempty,min=4,bcryptlen"`
+	// Generator picks the short code strategy when ShortName is empty.
+	Generator string `json:"generator" binding:"omitempty,oneof=random sequence words"`
+}
+
+func (r CreateLinkRequest) toInput() model.LinkInput {
+	input := model.LinkInput{
+		OriginalUrl: r.OriginalUrl,
+		ShortName:   r.ShortName,
+		Generator:   r.Generator,
+		DomainID:    r.DomainID,
+		ExpiresAt:   r.ExpiresAt,
+		MaxVisits:   r.MaxVisits,
+	}
+	if r.Password != "" {
+		input.Password = &r.Password
+	}
+	return input
 }
 
 func (h *handler) CreateLink(ctx *gin.Context) {
@@ -96,8 +237,23 @@ func (h *handler) CreateLink(ctx *gin.Context) {
 		return
 	}
 
-	link, err := h.service.Create(ctx, r.OriginalUrl, r.ShortName)
+	link, err := h.service.Create(ctx, middleware.CurrentUser(ctx).ID, r.toInput())
 	if err != nil {
+		if errors.Is(err, &model.DomainNotFoundError{}) {
+			ctx.JSON(http.StatusUnprocessableEntity, utils.ErrorResponse{
+				Errors: map[string]string{"domain_id": "unknown domain"},
+			})
+			return
+		}
+
+		var disallowed *model.DisallowedURLError
+		if errors.As(err, &disallowed) {
+			ctx.JSON(http.StatusUnprocessableEntity, utils.ErrorResponse{
+				Errors: map[string]string{"original_url": disallowed.Reason},
+			})
+			return
+		}
+
 		if utils.IsDuplicateKeyError(err) {
 			ctx.JSON(http.StatusUnprocessableEntity, utils.ErrorResponse{
 				Errors: utils.FormatDuplicateKeyError(err, "short_name"),
@@ -105,7 +261,7 @@ func (h *handler) CreateLink(ctx *gin.Context) {
 			return
 		}
 
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Failed to create link"})
+		utils.AbortWithError(ctx, http.StatusBadRequest, "Failed to create link", err)
 		return
 	}
 
@@ -116,18 +272,18 @@ func (h *handler) GetLink(ctx *gin.Context) {
 	idParam := ctx.Param("id")
 	id, err := strconv.ParseInt(idParam, 0, 64)
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
 		return
 	}
 
-	link, err := h.service.Get(ctx, id)
+	link, err := h.service.Get(ctx, middleware.CurrentUser(ctx).ID, id)
 	if err != nil {
 		if errors.Is(err, &model.LinkNotFoundError{}) {
 			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 			return
 		}
 
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		utils.AbortWithError(ctx, http.StatusInternalServerError, "Failed to get link", err)
 		return
 	}
 
@@ -135,15 +291,38 @@ func (h *handler) GetLink(ctx *gin.Context) {
 }
 
 type UpdateLinkRequest struct {
-	OriginalUrl string `json:"original_url" binding:"required,url"`
-	ShortName   string `json:"short_name" binding:"omitempty,min=3,max=32"`
+	OriginalUrl string     `json:"original_url" binding:"required,url"`
+	ShortName   string     `json:"short_name" binding:"omitempty,min=3,max=32,notreserved"`
+	DomainID    *int64     `json:"domain_id"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+	MaxVisits   *int64     `json:"max_visits" binding:"omitempty,min=1"`
+	// Password is left unchanged when omitted unless RemovePassword is set.
+	Password       string `json:"password" binding:"omitempty,min=4,bcryptlen"`
+	RemovePassword bool   `json:"remove_password"`
+}
+
+func (r UpdateLinkRequest) toInput() model.LinkInput {
+	input := model.LinkInput{
+		OriginalUrl: r.OriginalUrl,
+		ShortName:   r.ShortName,
+		DomainID:    r.DomainID,
+		ExpiresAt:   r.ExpiresAt,
+		MaxVisits:   r.MaxVisits,
+	}
+	switch {
+	case r.RemovePassword:
+		input.Password = new(string)
+	case r.Password != "":
+		input.Password = &r.Password
+	}
+	return input
 }
 
 func (h *handler) UpdateLink(ctx *gin.Context) {
 	idParam := ctx.Param("id")
 	id, err := strconv.ParseInt(idParam, 0, 64)
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
 		return
 	}
 
@@ -162,13 +341,28 @@ func (h *handler) UpdateLink(ctx *gin.Context) {
 		return
 	}
 
-	link, err := h.service.Update(ctx, id, req.OriginalUrl, req.ShortName)
+	link, err := h.service.Update(ctx, middleware.CurrentUser(ctx).ID, id, req.toInput())
 	if err != nil {
 		if errors.Is(err, &model.LinkNotFoundError{}) {
 			ctx.JSON(http.StatusNotFound, gin.H{"error": "Link not found"})
 			return
 		}
 
+		if errors.Is(err, &model.DomainNotFoundError{}) {
+			ctx.JSON(http.StatusUnprocessableEntity, utils.ErrorResponse{
+				Errors: map[string]string{"domain_id": "unknown domain"},
+			})
+			return
+		}
+
+		var disallowed *model.DisallowedURLError
+		if errors.As(err, &disallowed) {
+			ctx.JSON(http.StatusUnprocessableEntity, utils.ErrorResponse{
+				Errors: map[string]string{"original_url": disallowed.Reason},
+			})
+			return
+		}
+
 		if utils.IsDuplicateKeyError(err) {
 			ctx.JSON(http.StatusUnprocessableEntity, utils.ErrorResponse{
 				Errors: utils.FormatDuplicateKeyError(err, "short_name"),
@@ -176,7 +370,7 @@ func (h *handler) UpdateLink(ctx *gin.Context) {
 			return
 		}
 
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Failed to update link"})
+		utils.AbortWithError(ctx, http.StatusBadRequest, "Failed to update link", err)
 		return
 	}
 
@@ -187,17 +381,42 @@ func (h *handler) DeleteLink(ctx *gin.Context) {
 	idParam := ctx.Param("id")
 	id, err := strconv.ParseInt(idParam, 0, 64)
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
 		return
 	}
-	if err := h.service.Delete(ctx, id); err != nil {
+	if err := h.service.Delete(ctx, middleware.CurrentUser(ctx).ID, id); err != nil {
 		if errors.Is(err, &model.LinkNotFoundError{}) {
 			ctx.JSON(http.StatusNotFound, gin.H{"error": "Link not found"})
 			return
 		}
 
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		utils.AbortWithError(ctx, http.StatusInternalServerError, "Failed to delete link", err)
 		return
 	}
 	ctx.Status(http.StatusNoContent)
 }
+
+func (h *handler) GetLinkHealth(ctx *gin.Context) {
+	id, err := strconv.ParseInt(ctx.Param("id"), 0, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	health, err := h.service.Health(ctx, middleware.CurrentUser(ctx).ID, id)
+	if err != nil {
+		if errors.Is(err, &model.LinkNotFoundError{}) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Link not found"})
+			return
+		}
+
+		utils.AbortWithError(ctx, http.StatusInternalServerError, "Failed to get link health", err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, health)
+}
+
+func (h *handler) GetCacheStats(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, h.service.CacheStats())
+}
@@ -0,0 +1,103 @@
+package link
+
+import (
+	"context"
+
+	"markoni23/url-shortener/internal/cache"
+	"markoni23/url-shortener/internal/model"
+	"markoni23/url-shortener/internal/pagination"
+	"markoni23/url-shortener/internal/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// tracedService wraps a Service with a span per call.
+type tracedService struct {
+	service Service
+}
+
+func NewTracedService(service Service) *tracedService {
+	return &tracedService{service: service}
+}
+
+func (t *tracedService) Count(ctx context.Context, ownerID int64, filter model.LinkFilter) (_ int64, err error) {
+	ctx, span := tracing.Start(ctx, "LinkService.Count", attribute.Int64("user.id", ownerID))
+	defer func() { tracing.End(span, err) }()
+	return t.service.Count(ctx, ownerID, filter)
+}
+
+func (t *tracedService) GetAll(ctx context.Context, ownerID, from, to int64, filter model.LinkFilter, sort pagination.Sort) (_ []model.Link, err error) {
+	ctx, span := tracing.Start(ctx, "LinkService.GetAll", attribute.Int64("user.id", ownerID))
+	defer func() { tracing.End(span, err) }()
+	return t.service.GetAll(ctx, ownerID, from, to, filter, sort)
+}
+
+func (t *tracedService) GetPage(ctx context.Context, ownerID int64, page pagination.Params, filter model.LinkFilter) (_ []model.Link, _ *pagination.Cursor, err error) {
+	ctx, span := tracing.Start(ctx, "LinkService.GetPage", attribute.Int64("user.id", ownerID))
+	defer func() { tracing.End(span, err) }()
+	return t.service.GetPage(ctx, ownerID, page, filter)
+}
+
+func (t *tracedService) Get(ctx context.Context, ownerID, id int64) (_ model.Link, err error) {
+	ctx, span := tracing.Start(ctx, "LinkService.Get", attribute.Int64("user.id", ownerID), attribute.Int64("link.id", id))
+	defer func() { tracing.EndIgnoring(span, err, &model.LinkNotFoundError{}) }()
+	return t.service.Get(ctx, ownerID, id)
+}
+
+func (t *tracedService) Create(ctx context.Context, ownerID int64, input model.LinkInput) (_ model.Link, err error) {
+	ctx, span := tracing.Start(ctx, "LinkService.Create", attribute.Int64("user.id", ownerID))
+	defer func() { tracing.End(span, err) }()
+	return t.service.Create(ctx, ownerID, input)
+}
+
+func (t *tracedService) Update(ctx context.Context, ownerID, id int64, input model.LinkInput) (_ model.Link, err error) {
+	ctx, span := tracing.Start(ctx, "LinkService.Update", attribute.Int64("user.id", ownerID), attribute.Int64("link.id", id))
+	defer func() { tracing.End(span, err) }()
+	return t.service.Update(ctx, ownerID, id, input)
+}
+
+func (t *tracedService) Delete(ctx context.Context, ownerID, id int64) (err error) {
+	ctx, span := tracing.Start(ctx, "LinkService.Delete", attribute.Int64("user.id", ownerID), attribute.Int64("link.id", id))
+	defer func() { tracing.End(span, err) }()
+	return t.service.Delete(ctx, ownerID, id)
+}
+
+func (t *tracedService) CreateBulk(ctx context.Context, ownerID int64, inputs []model.LinkInput) (_ []model.BulkItemResult, err error) {
+	ctx, span := tracing.Start(ctx, "LinkService.CreateBulk", attribute.Int64("user.id", ownerID), attribute.Int("links.count", len(inputs)))
+	defer func() { tracing.End(span, err) }()
+	return t.service.CreateBulk(ctx, ownerID, inputs)
+}
+
+func (t *tracedService) ValidateBulk(ctx context.Context, ownerID int64, inputs []model.LinkInput) (_ []model.BulkItemResult, err error) {
+	ctx, span := tracing.Start(ctx, "LinkService.ValidateBulk", attribute.Int64("user.id", ownerID), attribute.Int("links.count", len(inputs)))
+	defer func() { tracing.End(span, err) }()
+	return t.service.ValidateBulk(ctx, ownerID, inputs)
+}
+
+func (t *tracedService) DeleteBulk(ctx context.Context, ownerID int64, ids []int64) (_ []int64, err error) {
+	ctx, span := tracing.Start(ctx, "LinkService.DeleteBulk", attribute.Int64("user.id", ownerID), attribute.Int("links.count", len(ids)))
+	defer func() { tracing.End(span, err) }()
+	return t.service.DeleteBulk(ctx, ownerID, ids)
+}
+
+func (t *tracedService) Export(ctx context.Context, ownerID int64, fn func(model.LinkExport) error) (err error) {
+	ctx, span := tracing.Start(ctx, "LinkService.Export", attribute.Int64("user.id", ownerID))
+	defer func() { tracing.End(span, err) }()
+	return t.service.Export(ctx, ownerID, fn)
+}
+
+func (t *tracedService) ExistingShortNames(ctx context.Context, domainID *int64, shortNames []string) (_ map[string]bool, err error) {
+	ctx, span := tracing.Start(ctx, "LinkService.ExistingShortNames", attribute.Int("links.count", len(shortNames)))
+	defer func() { tracing.End(span, err) }()
+	return t.service.ExistingShortNames(ctx, domainID, shortNames)
+}
+
+func (t *tracedService) Health(ctx context.Context, ownerID, id int64) (_ model.LinkHealth, err error) {
+	ctx, span := tracing.Start(ctx, "LinkService.Health", attribute.Int64("user.id", ownerID), attribute.Int64("link.id", id))
+	defer func() { tracing.EndIgnoring(span, err, &model.LinkNotFoundError{}) }()
+	return t.service.Health(ctx, ownerID, id)
+}
+
+func (t *tracedService) CacheStats() cache.Stats {
+	return t.service.CacheStats()
+}
@@ -0,0 +1,414 @@
+package link
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"markoni23/url-shortener/internal/middleware"
+	"markoni23/url-shortener/internal/model"
+	"markoni23/url-shortener/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+const (
+	formatCSV    = "csv"
+	formatJSON   = "json"
+	formatNDJSON = "ndjson"
+
+	maxImportRows = 10000
+)
+
+var csvExportHeader = []string{
+	"id", "original_url", "short_name", "short_url", "expires_at", "max_visits", "visits", "created_at",
+}
+
+var contentTypes = map[string]string{
+	formatCSV:    "text/csv; charset=utf-8",
+	formatJSON:   "application/json; charset=utf-8",
+	formatNDJSON: "application/x-ndjson",
+}
+
+type ImportLinksResponse struct {
+	DryRun  bool                   `json:"dry_run"`
+	Results []model.BulkItemResult `json:"results"`
+}
+
+func (h *handler) ExportLinks(ctx *gin.Context) {
+	format := ctx.DefaultQuery("format", formatJSON)
+	contentType, ok := contentTypes[format]
+	if !ok {
+		ctx.JSON(http.StatusBadRequest, utils.SimpleErrorResponse{
+			Error: "format must be one of csv, json, ndjson",
+		})
+		return
+	}
+
+	ctx.Header("Content-Type", contentType)
+	ctx.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="links.%s"`, format))
+	ctx.Status(http.StatusOK)
+
+	w := newExportWriter(format, ctx.Writer)
+	err := h.service.Export(ctx, middleware.CurrentUser(ctx).ID, w.write)
+	if err == nil {
+		err = w.close()
+	}
+	if err != nil {
+		// The status line is already sent, so the best we can do is to
+		// cut the stream short and log the reason.
+		slog.ErrorContext(ctx.Request.Context(), "links export failed", "error", err)
+		_ = ctx.Error(err)
+		ctx.Abort()
+	}
+}
+
+type exportWriter struct {
+	format string
+	w      io.Writer
+	csv    *csv.Writer
+	json   *json.Encoder
+	count  int
+}
+
+func newExportWriter(format string, w io.Writer) *exportWriter {
+	return &exportWriter{
+		format: format,
+		w:      w,
+		csv:    csv.NewWriter(w),
+		json:   json.NewEncoder(w),
+	}
+}
+
+func (e *exportWriter) write(link model.LinkExport) error {
+	defer func() { e.count++ }()
+
+	switch e.format {
+	case formatCSV:
+		if e.count == 0 {
+			if err := e.csv.Write(csvExportHeader); err != nil {
+				return err
+			}
+		}
+		if err := e.csv.Write(linkToCSV(link)); err != nil {
+			return err
+		}
+		e.csv.Flush()
+		return e.csv.Error()
+	case formatJSON:
+		sep := ","
+		if e.count == 0 {
+			sep = "["
+		}
+		if _, err := io.WriteString(e.w, sep); err != nil {
+			return err
+		}
+		return e.json.Encode(link)
+	default:
+		return e.json.Encode(link)
+	}
+}
+
+func (e *exportWriter) close() error {
+	switch e.format {
+	case formatCSV:
+		if e.count == 0 {
+			if err := e.csv.Write(csvExportHeader); err != nil {
+				return err
+			}
+		}
+		e.csv.Flush()
+		return e.csv.Error()
+	case formatJSON:
+		closing := "]\n"
+		if e.count == 0 {
+			closing = "[]\n"
+		}
+		_, err := io.WriteString(e.w, closing)
+		return err
+	default:
+		return nil
+	}
+}
+
+func linkToCSV(link model.LinkExport) []string {
+	var expiresAt, maxVisits string
+	if link.ExpiresAt != nil {
+		expiresAt = link.ExpiresAt.Format(time.RFC3339)
+	}
+	if link.MaxVisits != nil {
+		maxVisits = strconv.FormatInt(*link.MaxVisits, 10)
+	}
+
+	return []string{
+		strconv.FormatInt(link.ID, 10),
+		link.OriginalUrl,
+		link.ShortName,
+		link.ShortUrl,
+		expiresAt,
+		maxVisits,
+		strconv.FormatInt(link.Visits, 10),
+		link.CreatedAt.Format(time.RFC3339),
+	}
+}
+
+type importRow struct {
+	request CreateLinkRequest
+	errors  map[string]string
+}
+
+func (h *handler) ImportLinks(ctx *gin.Context) {
+	format := ctx.DefaultQuery("format", formatJSON)
+	if _, ok := contentTypes[format]; !ok {
+		ctx.JSON(http.StatusBadRequest, utils.SimpleErrorResponse{
+			Error: "format must be one of csv, json, ndjson",
+		})
+		return
+	}
+
+	dryRun, err := strconv.ParseBool(ctx.DefaultQuery("dry_run", "false"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, utils.SimpleErrorResponse{
+			Error: "invalid 'dry_run' value",
+		})
+		return
+	}
+
+	// All imported links go into one namespace, so that duplicate short
+	// names can be detected across the whole import. Rows may repeat the
+	// domain_id of the import but not name another one.
+	var domainID *int64
+	if raw := ctx.Query("domain_id"); raw != "" {
+		id, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, utils.SimpleErrorResponse{
+				Error: "invalid 'domain_id' value",
+			})
+			return
+		}
+		domainID = &id
+	}
+
+	rows, err := readImportRows(format, ctx.Request.Body)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, utils.SimpleErrorResponse{
+			Error: err.Error(),
+		})
+		return
+	}
+
+	results := make([]model.BulkItemResult, len(rows))
+	inputs := make([]model.LinkInput, len(rows))
+	seen := make(map[string]int, len(rows))
+	var shortNames []string
+	invalid := false
+
+	for i, row := range rows {
+		results[i].Index = i
+		if row.errors == nil {
+			if err := binding.Validator.ValidateStruct(row.request); err != nil {
+				row.errors = utils.FormatValidationErrors(err)
+			}
+		}
+
+		if rowDomain := row.request.DomainID; row.errors == nil && rowDomain != nil && (domainID == nil || *rowDomain != *domainID) {
+			row.errors = map[string]string{"domain_id": "domain_id must match the domain_id of the import"}
+		}
+
+		if name := row.request.ShortName; name != "" && row.errors == nil {
+			if _, ok := seen[strings.ToLower(name)]; ok {
+				row.errors = map[string]string{"short_name": "short name is duplicated in the import"}
+			} else {
+				seen[strings.ToLower(name)] = i
+				shortNames = append(shortNames, name)
+			}
+		}
+
+		if row.errors != nil {
+			results[i].Errors = row.errors
+			invalid = true
+			continue
+		}
+		inputs[i] = row.request.toInput()
+		inputs[i].DomainID = domainID
+	}
+
+	if dryRun {
+		// Rows that passed the request validation also go through the
+		// checks the import itself would make.
+		var valid []int
+		for i := range results {
+			if results[i].Errors == nil {
+				valid = append(valid, i)
+			}
+		}
+		validInputs := make([]model.LinkInput, len(valid))
+		for j, i := range valid {
+			validInputs[j] = inputs[i]
+		}
+		checked, err := h.service.ValidateBulk(ctx, middleware.CurrentUser(ctx).ID, validInputs)
+		if err != nil {
+			utils.AbortWithError(ctx, http.StatusInternalServerError, "Failed to check links", err)
+			return
+		}
+		for j, i := range valid {
+			results[i].Errors = checked[j].Errors
+		}
+
+		existing, err := h.service.ExistingShortNames(ctx, domainID, shortNames)
+		if err != nil {
+			utils.AbortWithError(ctx, http.StatusInternalServerError, "Failed to check short names", err)
+			return
+		}
+		for name := range existing {
+			i := seen[strings.ToLower(name)]
+			if results[i].Errors == nil {
+				results[i].Errors = make(map[string]string)
+			}
+			results[i].Errors["short_name"] = "short name already in use"
+		}
+
+		ctx.JSON(http.StatusOK, ImportLinksResponse{DryRun: true, Results: results})
+		return
+	}
+
+	if invalid {
+		ctx.JSON(http.StatusUnprocessableEntity, ImportLinksResponse{Results: results})
+		return
+	}
+
+	if !middleware.ChargeRateLimit(ctx, len(inputs)) {
+		return
+	}
+
+	results, err = h.service.CreateBulk(ctx, middleware.CurrentUser(ctx).ID, inputs)
+	if err != nil {
+		if errors.Is(err, &model.BulkRejectedError{}) {
+			ctx.JSON(http.StatusUnprocessableEntity, ImportLinksResponse{Results: results})
+			return
+		}
+
+		utils.AbortWithError(ctx, http.StatusInternalServerError, "Failed to import links", err)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, ImportLinksResponse{Results: results})
+}
+
+func readImportRows(format string, body io.Reader) ([]importRow, error) {
+	var rows []importRow
+	appendRow := func(row importRow) error {
+		if len(rows) >= maxImportRows {
+			return fmt.Errorf("import is limited to %d links", maxImportRows)
+		}
+		rows = append(rows, row)
+		return nil
+	}
+
+	switch format {
+	case formatCSV:
+		reader := csv.NewReader(body)
+		reader.FieldsPerRecord = -1
+
+		header, err := reader.Read()
+		if err != nil {
+			return nil, errors.New("invalid csv header")
+		}
+		columns := make(map[string]int, len(header))
+		for i, name := range header {
+			columns[strings.TrimSpace(name)] = i
+		}
+		if _, ok := columns["original_url"]; !ok {
+			return nil, errors.New("csv header must contain original_url")
+		}
+
+		for {
+			record, err := reader.Read()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				return nil, fmt.Errorf("invalid csv: %w", err)
+			}
+			if err := appendRow(csvToImportRow(columns, record)); err != nil {
+				return nil, err
+			}
+		}
+	case formatJSON:
+		decoder := json.NewDecoder(body)
+		if token, err := decoder.Token(); err != nil || token != json.Delim('[') {
+			return nil, errors.New("json import must be an array of links")
+		}
+		for decoder.More() {
+			var r CreateLinkRequest
+			if err := decoder.Decode(&r); err != nil {
+				return nil, fmt.Errorf("invalid json: %w", err)
+			}
+			if err := appendRow(importRow{request: r}); err != nil {
+				return nil, err
+			}
+		}
+	case formatNDJSON:
+		decoder := json.NewDecoder(body)
+		for {
+			var r CreateLinkRequest
+			err := decoder.Decode(&r)
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				return nil, fmt.Errorf("invalid ndjson: %w", err)
+			}
+			if err := appendRow(importRow{request: r}); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return rows, nil
+}
+
+func csvToImportRow(columns map[string]int, record []string) importRow {
+	value := func(name string) string {
+		i, ok := columns[name]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	row := importRow{
+		request: CreateLinkRequest{
+			OriginalUrl: value("original_url"),
+			ShortName:   value("short_name"),
+		},
+	}
+
+	if raw := value("expires_at"); raw != "" {
+		expiresAt, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			row.errors = map[string]string{"expires_at": "must be an RFC 3339 timestamp"}
+			return row
+		}
+		row.request.ExpiresAt = &expiresAt
+	}
+
+	if raw := value("max_visits"); raw != "" {
+		maxVisits, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			row.errors = map[string]string{"max_visits": "must be an integer"}
+			return row
+		}
+		row.request.MaxVisits = &maxVisits
+	}
+
+	return row
+}
@@ -2,23 +2,34 @@ package linkvisit
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"markoni23/url-shortener/internal/metrics"
+	"markoni23/url-shortener/internal/middleware"
 	"markoni23/url-shortener/internal/model"
+	"markoni23/url-shortener/internal/pagination"
+	"markoni23/url-shortener/internal/utils"
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
 type VisitService interface {
-	GetAll(ctx context.Context, from, to int64) ([]model.LinkVisit, error)
+	GetAll(ctx context.Context, ownerID, from, to int64, filter model.VisitFilter, sort pagination.Sort) ([]model.LinkVisit, error)
+	GetPage(ctx context.Context, ownerID int64, page pagination.Params, filter model.VisitFilter) ([]model.LinkVisit, *pagination.Cursor, error)
 	Visit(ctx *gin.Context, link model.Link) error
-	Count(ctx context.Context) (int64, error)
+	RecordFailedUnlock(ctx *gin.Context, link model.Link) error
+	Count(ctx context.Context, ownerID int64, filter model.VisitFilter) (int64, error)
+	Stats(ctx context.Context, linkID int64, from, to time.Time, interval string) (model.LinkStats, error)
 }
 
 type LinkService interface {
-	GetLinkByShortName(ctx context.Context, shortName string) (model.Link, error)
+	GetLinkByShortName(ctx context.Context, host, shortName string) (model.Link, error)
+	Get(ctx context.Context, ownerID, id int64) (model.Link, error)
+	VerifyPassword(link model.Link, password string) bool
 }
 
 type handler struct {
@@ -34,21 +45,95 @@ func NewHandler(visitService VisitService, linkService LinkService) *handler {
 }
 
 func (h *handler) VisistLink(ctx *gin.Context) {
+	link, ok := h.resolveLink(ctx)
+	if !ok {
+		return
+	}
+
+	if link.HasPassword {
+		metrics.Redirects.WithLabelValues(metrics.RedirectPasswordRequired).Inc()
+		renderUnlockForm(ctx, http.StatusOK, "")
+		return
+	}
+
+	if err := h.visitService.Visit(ctx, link); err != nil {
+		h.abortVisit(ctx, err)
+		return
+	}
+	metrics.Redirects.WithLabelValues(metrics.RedirectResolved).Inc()
+}
+
+// abortVisit writes the response for a link that cannot be visited. Visit
+// fails with LinkExpiredError when the last redirect was used up since the
+// link was resolved.
+func (h *handler) abortVisit(ctx *gin.Context, err error) {
+	if errors.Is(err, &model.LinkExpiredError{}) {
+		metrics.Redirects.WithLabelValues(metrics.RedirectExpired).Inc()
+		ctx.AbortWithStatusJSON(http.StatusGone, gin.H{"error": err.Error()})
+		return
+	}
+
+	utils.AbortWithError(ctx, http.StatusInternalServerError, "Failed to record visit", err)
+}
+
+// resolveLink looks up the link for the :code parameter and writes an error
+// response when it does not exist or can no longer be visited.
+func (h *handler) resolveLink(ctx *gin.Context) (model.Link, bool) {
 	code := ctx.Param("code")
 
-	link, err := h.linkService.GetLinkByShortName(ctx, code)
+	link, err := h.linkService.GetLinkByShortName(ctx, ctx.Request.Host, code)
+	if err != nil {
+		if errors.Is(err, &model.LinkNotFoundError{}) {
+			metrics.Redirects.WithLabelValues(metrics.RedirectNotFound).Inc()
+			ctx.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return model.Link{}, false
+		}
+
+		utils.AbortWithError(ctx, http.StatusInternalServerError, "Failed to resolve link", err)
+		return model.Link{}, false
+	}
+
+	// Whether redirects are left is only known when Visit claims one.
+	if link.IsExpired(time.Now()) {
+		h.abortVisit(ctx, &model.LinkExpiredError{})
+		return model.Link{}, false
+	}
+
+	return link, true
+}
+
+// GetVisits lists the visits of the user's links, newest first. With
+// ?after= or ?limit= it pages by cursor, which stays fast on deep pages,
+// otherwise it serves the react-admin range format.
+func (h *handler) GetVisits(ctx *gin.Context) {
+	user := middleware.CurrentUser(ctx)
 
+	filter, err := parseVisitFilter(ctx)
 	if err != nil {
 		ctx.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	if err := h.visitService.Visit(ctx, link); err != nil {
+
+	page, paged, err := pagination.FromQuery(ctx)
+	if err != nil {
 		ctx.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-}
+	if paged {
+		// Cursors encode the position in the default order only.
+		if _, ok := ctx.GetQuery("sort"); ok {
+			ctx.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "sort is not supported with cursor pagination"})
+			return
+		}
+		res, next, err := h.visitService.GetPage(ctx, user.ID, page, filter)
+		if err != nil {
+			utils.AbortWithError(ctx, http.StatusInternalServerError, "Failed to list visits", err)
+			return
+		}
+		pagination.Respond(ctx, res, next, page.Limit)
+		return
+	}
 
-func (h *handler) GetVisits(ctx *gin.Context) {
 	rangeString := ctx.DefaultQuery("range", "[0,10]")
 
 	rangeWithoutBrackets := strings.Trim(rangeString, "[]")
@@ -56,6 +141,7 @@ func (h *handler) GetVisits(ctx *gin.Context) {
 
 	if len(fromToSlice) != 2 {
 		ctx.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "wrong range format"})
+		return
 	}
 
 	from, err := strconv.Atoi(strings.TrimSpace(fromToSlice[0]))
@@ -70,15 +156,27 @@ func (h *handler) GetVisits(ctx *gin.Context) {
 		return
 	}
 
-	res, err := h.visitService.GetAll(ctx, int64(from), int64(to))
+	sort, err := pagination.ParseSort(ctx, visitSortFields, pagination.Sort{Field: "created_at", Desc: true})
 	if err != nil {
-		ctx.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	count, err := h.visitService.Count(ctx)
+	res, err := h.visitService.GetAll(ctx, user.ID, int64(from), int64(to), filter, sort)
 	if err != nil {
-		ctx.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		var invalid *model.InvalidParamsError
+		if errors.As(err, &invalid) {
+			ctx.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": invalid.Reason})
+			return
+		}
+
+		utils.AbortWithError(ctx, http.StatusInternalServerError, "Failed to list visits", err)
+		return
+	}
+
+	count, err := h.visitService.Count(ctx, user.ID, filter)
+	if err != nil {
+		utils.AbortWithError(ctx, http.StatusInternalServerError, "Failed to count visits", err)
 		return
 	}
 
@@ -87,3 +185,75 @@ func (h *handler) GetVisits(ctx *gin.Context) {
 
 	ctx.JSON(http.StatusOK, res)
 }
+
+// visitSortFields are the fields the visits list can be sorted by.
+var visitSortFields = []string{"id", "created_at", "link_id", "status", "ip", "country", "browser"}
+
+// visitListFilter is the react-admin filter parameter of the visits list.
+type visitListFilter struct {
+	LinkID       *int64  `json:"link_id"`
+	IP           *string `json:"ip"`
+	Status       *int32  `json:"status"`
+	Browser      *string `json:"browser"`
+	OS           *string `json:"os"`
+	DeviceType   *string `json:"device_type"`
+	Country      *string `json:"country"`
+	IsBot        *bool   `json:"is_bot"`
+	CreatedAtGte string  `json:"created_at_gte"`
+	CreatedAtLte string  `json:"created_at_lte"`
+}
+
+// parseVisitFilter reads the react-admin filter parameter and the optional
+// browser, os, device_type, country and is_bot query parameters, which take
+// precedence.
+func parseVisitFilter(ctx *gin.Context) (model.VisitFilter, error) {
+	var raw visitListFilter
+	if err := pagination.ParseFilter(ctx, &raw); err != nil {
+		return model.VisitFilter{}, err
+	}
+
+	filter := model.VisitFilter{
+		Browser:    raw.Browser,
+		OS:         raw.OS,
+		DeviceType: raw.DeviceType,
+		IsBot:      raw.IsBot,
+		LinkID:     raw.LinkID,
+		IP:         raw.IP,
+		Status:     raw.Status,
+	}
+	if raw.Country != nil {
+		country := strings.ToUpper(*raw.Country)
+		filter.Country = &country
+	}
+
+	var err error
+	if filter.CreatedFrom, err = pagination.ParseDate(raw.CreatedAtGte, false); err != nil {
+		return model.VisitFilter{}, err
+	}
+	if filter.CreatedTo, err = pagination.ParseDate(raw.CreatedAtLte, true); err != nil {
+		return model.VisitFilter{}, err
+	}
+
+	if v, ok := ctx.GetQuery("browser"); ok {
+		filter.Browser = &v
+	}
+	if v, ok := ctx.GetQuery("os"); ok {
+		filter.OS = &v
+	}
+	if v, ok := ctx.GetQuery("device_type"); ok {
+		filter.DeviceType = &v
+	}
+	if v, ok := ctx.GetQuery("country"); ok {
+		country := strings.ToUpper(v)
+		filter.Country = &country
+	}
+	if v, ok := ctx.GetQuery("is_bot"); ok {
+		isBot, err := strconv.ParseBool(v)
+		if err != nil {
+			return model.VisitFilter{}, errors.New("invalid 'is_bot' value")
+		}
+		filter.IsBot = &isBot
+	}
+
+	return filter, nil
+}
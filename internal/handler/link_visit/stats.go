@@ -0,0 +1,68 @@
+package linkvisit
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"markoni23/url-shortener/internal/middleware"
+	"markoni23/url-shortener/internal/model"
+	"markoni23/url-shortener/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+const defaultStatsRange = 30 * 24 * time.Hour
+
+func (h *handler) GetLinkStats(ctx *gin.Context) {
+	id, err := strconv.ParseInt(ctx.Param("id"), 0, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	to := time.Now()
+	if raw := ctx.Query("to"); raw != "" {
+		to, err = time.Parse(time.RFC3339, raw)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid 'to' value"})
+			return
+		}
+	}
+
+	from := to.Add(-defaultStatsRange)
+	if raw := ctx.Query("from"); raw != "" {
+		from, err = time.Parse(time.RFC3339, raw)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid 'from' value"})
+			return
+		}
+	}
+
+	link, err := h.linkService.Get(ctx, middleware.CurrentUser(ctx).ID, id)
+	if err != nil {
+		if errors.Is(err, &model.LinkNotFoundError{}) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+
+		utils.AbortWithError(ctx, http.StatusInternalServerError, "Failed to get link", err)
+		return
+	}
+
+	interval := ctx.DefaultQuery("interval", "day")
+	stats, err := h.visitService.Stats(ctx, link.ID, from, to, interval)
+	if err != nil {
+		var invalid *model.InvalidParamsError
+		if errors.As(err, &invalid) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": invalid.Reason})
+			return
+		}
+
+		utils.AbortWithError(ctx, http.StatusInternalServerError, "Failed to get link stats", err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, stats)
+}
@@ -0,0 +1,93 @@
+package linkvisit
+
+import (
+	"context"
+	"time"
+
+	"markoni23/url-shortener/internal/model"
+	"markoni23/url-shortener/internal/pagination"
+	"markoni23/url-shortener/internal/tracing"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// tracedVisitService wraps a VisitService with a span per call.
+type tracedVisitService struct {
+	service VisitService
+}
+
+func NewTracedVisitService(service VisitService) *tracedVisitService {
+	return &tracedVisitService{service: service}
+}
+
+func (t *tracedVisitService) GetAll(ctx context.Context, ownerID, from, to int64, filter model.VisitFilter, sort pagination.Sort) (_ []model.LinkVisit, err error) {
+	ctx, span := tracing.Start(ctx, "VisitService.GetAll", attribute.Int64("user.id", ownerID))
+	defer func() { tracing.End(span, err) }()
+	return t.service.GetAll(ctx, ownerID, from, to, filter, sort)
+}
+
+func (t *tracedVisitService) GetPage(ctx context.Context, ownerID int64, page pagination.Params, filter model.VisitFilter) (_ []model.LinkVisit, _ *pagination.Cursor, err error) {
+	ctx, span := tracing.Start(ctx, "VisitService.GetPage", attribute.Int64("user.id", ownerID))
+	defer func() { tracing.End(span, err) }()
+	return t.service.GetPage(ctx, ownerID, page, filter)
+}
+
+// Visit and RecordFailedUnlock take the gin context, whose request context
+// holds the parent span.
+func (t *tracedVisitService) Visit(ctx *gin.Context, link model.Link) (err error) {
+	_, span := tracing.Start(ctx, "VisitService.Visit", attribute.Int64("link.id", link.ID))
+	defer func() { tracing.EndIgnoring(span, err, &model.LinkExpiredError{}) }()
+	return t.service.Visit(ctx, link)
+}
+
+func (t *tracedVisitService) RecordFailedUnlock(ctx *gin.Context, link model.Link) (err error) {
+	_, span := tracing.Start(ctx, "VisitService.RecordFailedUnlock", attribute.Int64("link.id", link.ID))
+	defer func() { tracing.End(span, err) }()
+	return t.service.RecordFailedUnlock(ctx, link)
+}
+
+func (t *tracedVisitService) Count(ctx context.Context, ownerID int64, filter model.VisitFilter) (_ int64, err error) {
+	ctx, span := tracing.Start(ctx, "VisitService.Count", attribute.Int64("user.id", ownerID))
+	defer func() { tracing.End(span, err) }()
+	return t.service.Count(ctx, ownerID, filter)
+}
+
+func (t *tracedVisitService) Stats(ctx context.Context, linkID int64, from, to time.Time, interval string) (_ model.LinkStats, err error) {
+	ctx, span := tracing.Start(ctx, "VisitService.Stats",
+		attribute.Int64("link.id", linkID),
+		attribute.String("stats.interval", interval),
+	)
+	defer func() { tracing.End(span, err) }()
+	return t.service.Stats(ctx, linkID, from, to, interval)
+}
+
+// tracedLinkService wraps the LinkService used on the redirect path with a
+// span per call.
+type tracedLinkService struct {
+	service LinkService
+}
+
+func NewTracedLinkService(service LinkService) *tracedLinkService {
+	return &tracedLinkService{service: service}
+}
+
+func (t *tracedLinkService) GetLinkByShortName(ctx context.Context, host, shortName string) (_ model.Link, err error) {
+	ctx, span := tracing.Start(ctx, "LinkService.GetLinkByShortName",
+		attribute.String("link.host", host),
+		attribute.String("link.short_name", shortName),
+	)
+	defer func() { tracing.EndIgnoring(span, err, &model.LinkNotFoundError{}) }()
+	return t.service.GetLinkByShortName(ctx, host, shortName)
+}
+
+func (t *tracedLinkService) Get(ctx context.Context, ownerID, id int64) (_ model.Link, err error) {
+	ctx, span := tracing.Start(ctx, "LinkService.Get", attribute.Int64("user.id", ownerID), attribute.Int64("link.id", id))
+	defer func() { tracing.EndIgnoring(span, err, &model.LinkNotFoundError{}) }()
+	return t.service.Get(ctx, ownerID, id)
+}
+
+// VerifyPassword has no context to attach a span to.
+func (t *tracedLinkService) VerifyPassword(link model.Link, password string) bool {
+	return t.service.VerifyPassword(link, password)
+}
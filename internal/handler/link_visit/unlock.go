@@ -0,0 +1,79 @@
+package linkvisit
+
+import (
+	"html/template"
+	"net/http"
+
+	"markoni23/url-shortener/internal/metrics"
+	"markoni23/url-shortener/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+var unlockTemplate = template.Must(template.New("unlock").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta name="robots" content="noindex">
+<title>Protected link</title>
+<style>
+body { font-family: sans-serif; display: flex; justify-content: center; margin-top: 15vh; }
+form { display: flex; flex-direction: column; gap: 0.75rem; width: 18rem; }
+.error { color: #b00020; }
+</style>
+</head>
+<body>
+<form method="post" action="{{.Action}}">
+<h1>Protected link</h1>
+<label for="password">Enter the password to continue</label>
+<input id="password" name="password" type="password" autocomplete="current-password" required autofocus>
+{{if .Error}}<p class="error">{{.Error}}</p>{{end}}
+<button type="submit">Unlock</button>
+</form>
+</body>
+</html>
+`))
+
+type unlockPage struct {
+	Action string
+	Error  string
+}
+
+func renderUnlockForm(ctx *gin.Context, status int, errorMessage string) {
+	ctx.Header("Content-Type", "text/html; charset=utf-8")
+	ctx.Header("Cache-Control", "no-store")
+	ctx.Status(status)
+
+	page := unlockPage{
+		Action: ctx.Request.URL.Path,
+		Error:  errorMessage,
+	}
+	if err := unlockTemplate.Execute(ctx.Writer, page); err != nil {
+		_ = ctx.Error(err)
+	}
+}
+
+func (h *handler) UnlockLink(ctx *gin.Context) {
+	link, ok := h.resolveLink(ctx)
+	if !ok {
+		return
+	}
+
+	if !h.linkService.VerifyPassword(link, ctx.PostForm("password")) {
+		metrics.Redirects.WithLabelValues(metrics.RedirectBlocked).Inc()
+		if err := h.visitService.RecordFailedUnlock(ctx, link); err != nil {
+			utils.AbortWithError(ctx, http.StatusInternalServerError, "Failed to record visit", err)
+			return
+		}
+
+		renderUnlockForm(ctx, http.StatusUnauthorized, "Wrong password")
+		return
+	}
+
+	if err := h.visitService.Visit(ctx, link); err != nil {
+		h.abortVisit(ctx, err)
+		return
+	}
+	metrics.Redirects.WithLabelValues(metrics.RedirectResolved).Inc()
+}
@@ -0,0 +1,75 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+)
+
+// Buffer is a slog.Handler that keeps records until Replay hands them to
+// another handler. It stands in for the logger while the configuration
+// that selects the log format and level is being read.
+type Buffer struct {
+	store *bufferStore
+	// wrap applies the WithAttrs and WithGroup calls made on this handler.
+	wrap []func(slog.Handler) slog.Handler
+}
+
+type bufferStore struct {
+	mu      sync.Mutex
+	records []bufferedRecord
+}
+
+type bufferedRecord struct {
+	record slog.Record
+	wrap   []func(slog.Handler) slog.Handler
+}
+
+func NewBuffer() *Buffer {
+	return &Buffer{store: &bufferStore{}}
+}
+
+// Enabled keeps every level; Replay filters against the real handler.
+func (b *Buffer) Enabled(context.Context, slog.Level) bool {
+	return true
+}
+
+func (b *Buffer) Handle(_ context.Context, r slog.Record) error {
+	b.store.mu.Lock()
+	defer b.store.mu.Unlock()
+	b.store.records = append(b.store.records, bufferedRecord{record: r.Clone(), wrap: b.wrap})
+	return nil
+}
+
+func (b *Buffer) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return b.with(func(h slog.Handler) slog.Handler { return h.WithAttrs(attrs) })
+}
+
+func (b *Buffer) WithGroup(name string) slog.Handler {
+	return b.with(func(h slog.Handler) slog.Handler { return h.WithGroup(name) })
+}
+
+func (b *Buffer) with(fn func(slog.Handler) slog.Handler) *Buffer {
+	wrap := make([]func(slog.Handler) slog.Handler, len(b.wrap), len(b.wrap)+1)
+	copy(wrap, b.wrap)
+	return &Buffer{store: b.store, wrap: append(wrap, fn)}
+}
+
+// Replay passes the buffered records to h in the order they were logged,
+// skipping those below the level h is enabled for, and empties the buffer.
+func (b *Buffer) Replay(ctx context.Context, h slog.Handler) {
+	b.store.mu.Lock()
+	records := b.store.records
+	b.store.records = nil
+	b.store.mu.Unlock()
+
+	for _, buffered := range records {
+		handler := h
+		for _, fn := range buffered.wrap {
+			handler = fn(handler)
+		}
+		if handler.Enabled(ctx, buffered.record.Level) {
+			_ = handler.Handle(ctx, buffered.record)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestBufferReplay(t *testing.T) {
+	buffer := NewBuffer()
+	logger := slog.New(buffer)
+	logger.Debug("hidden")
+	logger.With("component", "config").WithGroup("env").Warn("invalid value", "key", "PORT")
+
+	var out bytes.Buffer
+	target, err := New(&out, FormatText, "info")
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	buffer.Replay(context.Background(), target.Handler())
+
+	got := out.String()
+	if strings.Contains(got, "hidden") {
+		t.Errorf("replayed a record below the target level:\n%s", got)
+	}
+	for _, want := range []string{"level=WARN", "msg=\"invalid value\"", "component=config", "env.key=PORT"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("output %q does not contain %q", got, want)
+		}
+	}
+
+	out.Reset()
+	buffer.Replay(context.Background(), target.Handler())
+	if out.Len() != 0 {
+		t.Errorf("second Replay wrote %q, want nothing", out.String())
+	}
+}
@@ -0,0 +1,71 @@
+package metrics
+
+import (
+	"context"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// BusinessQueries counts the totals exported as business gauges.
+type BusinessQueries interface {
+	CountLinks(ctx context.Context) (int64, error)
+	EstimateLinkVisits(ctx context.Context) (int64, error)
+}
+
+// BusinessGauges exports the total number of links and visits. The totals
+// are refreshed periodically rather than on every scrape, and the visits
+// total is the planner estimate, since counting a large visits table takes
+// seconds.
+type BusinessGauges struct {
+	queries BusinessQueries
+	links   atomic.Int64
+	visits  atomic.Int64
+}
+
+func NewBusinessGauges(queries BusinessQueries) *BusinessGauges {
+	g := &BusinessGauges{queries: queries}
+	promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "url_shortener_links",
+		Help: "Total number of links.",
+	}, func() float64 { return float64(g.links.Load()) })
+	promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "url_shortener_link_visits_estimated",
+		Help: "Estimated total number of recorded visits.",
+	}, func() float64 { return float64(g.visits.Load()) })
+	return g
+}
+
+// Run refreshes the totals every interval. It blocks until ctx is
+// cancelled.
+func (g *BusinessGauges) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		g.refresh(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (g *BusinessGauges) refresh(ctx context.Context) {
+	if links, err := g.queries.CountLinks(ctx); err != nil {
+		slog.ErrorContext(ctx, "failed to count links", "error", err)
+	} else {
+		g.links.Store(links)
+	}
+
+	if visits, err := g.queries.EstimateLinkVisits(ctx); err != nil {
+		slog.ErrorContext(ctx, "failed to estimate visits", "error", err)
+	} else {
+		g.visits.Store(visits)
+	}
+}
@@ -0,0 +1,46 @@
+// Package metrics defines the Prometheus metrics of the service. They are
+// registered with the default registry, which also holds the Go runtime and
+// process collectors.
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Results of a redirect request, the values of the result label of
+// Redirects.
+const (
+	RedirectResolved = "resolved"
+	RedirectNotFound = "not_found"
+	RedirectExpired  = "expired"
+	// RedirectPasswordRequired means the password form of a protected link
+	// was shown. Unlocking it is counted again, as resolved or blocked.
+	RedirectPasswordRequired = "password_required"
+	// RedirectBlocked means a wrong password was submitted for a link.
+	RedirectBlocked = "blocked"
+)
+
+var (
+	HTTPRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_requests_total",
+		Help: "HTTP requests by method, route template and status code.",
+	}, []string{"method", "route", "status"})
+	HTTPRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "HTTP request latency by method and route template.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route"})
+	Redirects = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "url_shortener_redirects_total",
+		Help: "Short link requests by result: resolved, not_found, expired, password_required or blocked.",
+	}, []string{"result"})
+)
+
+// Handler serves all registered metrics.
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
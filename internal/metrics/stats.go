@@ -0,0 +1,78 @@
+package metrics
+
+import (
+	linkService "markoni23/url-shortener/internal/service/link"
+	visitService "markoni23/url-shortener/internal/service/link_visit"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	visitsQueuedDesc = prometheus.NewDesc("url_shortener_visits_queued",
+		"Visits waiting in the recorder queue.", nil, nil)
+	visitsWrittenDesc = prometheus.NewDesc("url_shortener_visits_written_total",
+		"Visits written to the database by the recorder.", nil, nil)
+	visitsFailedDesc = prometheus.NewDesc("url_shortener_visits_failed_total",
+		"Visits lost because their batch could not be written.", nil, nil)
+	visitsDroppedDesc = prometheus.NewDesc("url_shortener_visits_dropped_total",
+		"Visits dropped because the recorder queue was full or closed, or their link was deleted.", nil, nil)
+
+	linkCacheHitsDesc = prometheus.NewDesc("url_shortener_link_cache_hits_total",
+		"Short name lookups served from the in-process link cache.", nil, nil)
+	linkCacheMissesDesc = prometheus.NewDesc("url_shortener_link_cache_misses_total",
+		"Short name lookups not found in the in-process link cache.", nil, nil)
+	linkCacheSizeDesc = prometheus.NewDesc("url_shortener_link_cache_entries",
+		"Entries in the in-process link cache.", nil, nil)
+	linkCacheCapacityDesc = prometheus.NewDesc("url_shortener_link_cache_capacity",
+		"Maximum number of entries in the in-process link cache.", nil, nil)
+)
+
+// RegisterVisitRecorder exports the counters of the visit recorder.
+func RegisterVisitRecorder(recorder *visitService.Recorder) {
+	prometheus.MustRegister(recorderCollector{recorder: recorder})
+}
+
+// RegisterLinkCache exports the statistics of the link cache.
+func RegisterLinkCache(cache *linkService.LinkCache) {
+	prometheus.MustRegister(linkCacheCollector{cache: cache})
+}
+
+// recorderCollector reads the recorder statistics once per scrape.
+type recorderCollector struct {
+	recorder *visitService.Recorder
+}
+
+func (c recorderCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- visitsQueuedDesc
+	ch <- visitsWrittenDesc
+	ch <- visitsFailedDesc
+	ch <- visitsDroppedDesc
+}
+
+func (c recorderCollector) Collect(ch chan<- prometheus.Metric) {
+	stats := c.recorder.Stats()
+	ch <- prometheus.MustNewConstMetric(visitsQueuedDesc, prometheus.GaugeValue, float64(stats.Queued))
+	ch <- prometheus.MustNewConstMetric(visitsWrittenDesc, prometheus.CounterValue, float64(stats.Written))
+	ch <- prometheus.MustNewConstMetric(visitsFailedDesc, prometheus.CounterValue, float64(stats.Failed))
+	ch <- prometheus.MustNewConstMetric(visitsDroppedDesc, prometheus.CounterValue, float64(stats.Dropped))
+}
+
+// linkCacheCollector reads the cache statistics once per scrape.
+type linkCacheCollector struct {
+	cache *linkService.LinkCache
+}
+
+func (c linkCacheCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- linkCacheHitsDesc
+	ch <- linkCacheMissesDesc
+	ch <- linkCacheSizeDesc
+	ch <- linkCacheCapacityDesc
+}
+
+func (c linkCacheCollector) Collect(ch chan<- prometheus.Metric) {
+	stats := c.cache.Stats()
+	ch <- prometheus.MustNewConstMetric(linkCacheHitsDesc, prometheus.CounterValue, float64(stats.Hits))
+	ch <- prometheus.MustNewConstMetric(linkCacheMissesDesc, prometheus.CounterValue, float64(stats.Misses))
+	ch <- prometheus.MustNewConstMetric(linkCacheSizeDesc, prometheus.GaugeValue, float64(stats.Size))
+	ch <- prometheus.MustNewConstMetric(linkCacheCapacityDesc, prometheus.GaugeValue, float64(stats.Capacity))
+}
@@ -0,0 +1,61 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	"markoni23/url-shortener/internal/config"
+	"markoni23/url-shortener/internal/geoip"
+	linkService "markoni23/url-shortener/internal/service/link"
+	visitService "markoni23/url-shortener/internal/service/link_visit"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func TestStatsCollectors(t *testing.T) {
+	recorder := visitService.NewRecorder(nil, geoip.NopResolver{}, config.VisitsConfig{QueueSize: 10})
+	cache := linkService.NewLinkCache(config.LinkCacheConfig{Size: 100, TTL: time.Minute}, nil)
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(recorderCollector{recorder: recorder}, linkCacheCollector{cache: cache})
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatalf("Gather: %v", err)
+	}
+
+	got := make(map[string]*dto.MetricFamily)
+	for _, family := range families {
+		got[family.GetName()] = family
+	}
+
+	tests := []struct {
+		name  string
+		kind  dto.MetricType
+		value float64
+	}{
+		{name: "url_shortener_visits_queued", kind: dto.MetricType_GAUGE},
+		{name: "url_shortener_visits_written_total", kind: dto.MetricType_COUNTER},
+		{name: "url_shortener_visits_failed_total", kind: dto.MetricType_COUNTER},
+		{name: "url_shortener_visits_dropped_total", kind: dto.MetricType_COUNTER},
+		{name: "url_shortener_link_cache_hits_total", kind: dto.MetricType_COUNTER},
+		{name: "url_shortener_link_cache_misses_total", kind: dto.MetricType_COUNTER},
+		{name: "url_shortener_link_cache_entries", kind: dto.MetricType_GAUGE},
+		{name: "url_shortener_link_cache_capacity", kind: dto.MetricType_GAUGE, value: 100},
+	}
+	for _, tt := range tests {
+		family, ok := got[tt.name]
+		if !ok {
+			t.Errorf("%s is not exported", tt.name)
+			continue
+		}
+		if family.GetType() != tt.kind {
+			t.Errorf("%s is a %s, want %s", tt.name, family.GetType(), tt.kind)
+		}
+		metric := family.GetMetric()[0]
+		value := metric.GetGauge().GetValue() + metric.GetCounter().GetValue()
+		if value != tt.value {
+			t.Errorf("%s = %v, want %v", tt.name, value, tt.value)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"markoni23/url-shortener/internal/model"
+	"markoni23/url-shortener/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+const userContextKey = "user"
+
+type Authenticator interface {
+	Authenticate(ctx context.Context, apiKey string) (model.User, error)
+}
+
+// Auth rejects requests without a valid "Authorization: Bearer <key>" header
+// and stores the resolved user on the context for CurrentUser.
+func Auth(authenticator Authenticator) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if ctx.Request.Method == http.MethodOptions {
+			ctx.Next()
+			return
+		}
+
+		apiKey, ok := strings.CutPrefix(ctx.GetHeader("Authorization"), "Bearer ")
+		if !ok {
+			apiKey = ""
+		}
+
+		user, err := authenticator.Authenticate(ctx, strings.TrimSpace(apiKey))
+		if err != nil {
+			if errors.Is(err, &model.UnauthorizedError{}) {
+				ctx.Header("WWW-Authenticate", `Bearer realm="api"`)
+				ctx.AbortWithStatusJSON(http.StatusUnauthorized, utils.SimpleErrorResponse{
+					Error: err.Error(),
+				})
+				return
+			}
+
+			utils.AbortWithError(ctx, http.StatusInternalServerError, "failed to authenticate", err)
+			return
+		}
+
+		ctx.Set(userContextKey, user)
+		ctx.Next()
+	}
+}
+
+// CurrentUser returns the user resolved by Auth. It must only be used in
+// handlers mounted behind the Auth middleware.
+func CurrentUser(ctx *gin.Context) model.User {
+	return ctx.MustGet(userContextKey).(model.User)
+}
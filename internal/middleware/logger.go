@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Logger writes one access log line per request. Server errors are logged
+// at error level.
+func Logger() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		start := time.Now()
+		ctx.Next()
+
+		status := ctx.Writer.Status()
+		level := slog.LevelInfo
+		if status >= http.StatusInternalServerError {
+			level = slog.LevelError
+		}
+
+		attrs := []slog.Attr{
+			slog.String("method", ctx.Request.Method),
+			slog.String("path", ctx.Request.URL.Path),
+			slog.String("route", ctx.FullPath()),
+			slog.Int("status", status),
+			slog.Float64("duration_ms", float64(time.Since(start).Microseconds())/1000),
+			slog.String("client_ip", ctx.ClientIP()),
+			slog.Int("bytes", ctx.Writer.Size()),
+			slog.String("user_agent", ctx.Request.UserAgent()),
+		}
+		if len(ctx.Errors) > 0 {
+			attrs = append(attrs, slog.String("errors", ctx.Errors.String()))
+		}
+		slog.LogAttrs(ctx.Request.Context(), level, "request", attrs...)
+	}
+}
+
+// Recovery turns panics into 500 responses and logs them with their stack.
+func Recovery() gin.HandlerFunc {
+	return gin.CustomRecoveryWithWriter(nil, func(ctx *gin.Context, recovered any) {
+		slog.ErrorContext(ctx.Request.Context(), "panic while serving request",
+			"panic", recovered,
+			"stack", string(debug.Stack()),
+		)
+		ctx.AbortWithStatus(http.StatusInternalServerError)
+	})
+}
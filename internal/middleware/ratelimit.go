@@ -0,0 +1,93 @@
+package middleware
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"markoni23/url-shortener/internal/model"
+	"markoni23/url-shortener/internal/ratelimit"
+	"markoni23/url-shortener/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+const rateLimitChargeKey = "rateLimitCharge"
+
+// RateLimit takes a token from the caller's bucket for the named policy and
+// rejects the request with 429 once the bucket is empty. Authenticated
+// requests are keyed on the API key, anonymous ones on the client IP. If
+// the limiter itself fails the request is let through.
+func RateLimit(limiter ratelimit.Limiter, name string, policy ratelimit.Policy) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if ctx.Request.Method == http.MethodOptions {
+			ctx.Next()
+			return
+		}
+
+		if takeTokens(ctx, limiter, name, policy, 1) {
+			ctx.Next()
+		}
+	}
+}
+
+// RateLimitPerItem is RateLimit for endpoints that create several items in
+// one request. The number of items is only known once the handler has
+// parsed the body, so the handler charges it with ChargeRateLimit.
+func RateLimitPerItem(limiter ratelimit.Limiter, name string, policy ratelimit.Policy) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		ctx.Set(rateLimitChargeKey, func(cost int) bool {
+			return takeTokens(ctx, limiter, name, policy, cost)
+		})
+		ctx.Next()
+	}
+}
+
+// ChargeRateLimit takes cost tokens from the bucket set up by
+// RateLimitPerItem. It returns false after aborting the request with 429
+// when the bucket cannot pay, and true when no limit applies to the route.
+func ChargeRateLimit(ctx *gin.Context, cost int) bool {
+	if value, ok := ctx.Get(rateLimitChargeKey); ok {
+		if charge, ok := value.(func(int) bool); ok {
+			return charge(cost)
+		}
+	}
+	return true
+}
+
+func takeTokens(ctx *gin.Context, limiter ratelimit.Limiter, name string, policy ratelimit.Policy, cost int) bool {
+	res, err := limiter.Allow(ctx, name+":"+rateLimitKey(ctx), policy, cost)
+	if err != nil {
+		slog.ErrorContext(ctx.Request.Context(), "rate limiter failed", "limiter", name, "error", err)
+		return true
+	}
+
+	ctx.Header("RateLimit-Limit", strconv.Itoa(res.Limit))
+	ctx.Header("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+	ctx.Header("RateLimit-Reset", strconv.Itoa(ceilSeconds(res.ResetAfter)))
+
+	if !res.Allowed {
+		ctx.Header("Retry-After", strconv.Itoa(max(ceilSeconds(res.RetryAfter), 1)))
+		ctx.AbortWithStatusJSON(http.StatusTooManyRequests, utils.SimpleErrorResponse{
+			Error: "rate limit exceeded",
+		})
+		return false
+	}
+	return true
+}
+
+func rateLimitKey(ctx *gin.Context) string {
+	if value, ok := ctx.Get(userContextKey); ok {
+		if user, ok := value.(model.User); ok {
+			return fmt.Sprintf("key:%d", user.APIKeyID)
+		}
+	}
+	return "ip:" + ctx.ClientIP()
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
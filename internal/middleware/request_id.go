@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+
+	"markoni23/url-shortener/internal/logging"
+
+	sentrygin "github.com/getsentry/sentry-go/gin"
+	"github.com/gin-gonic/gin"
+)
+
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength bounds incoming request IDs, which end up in every log
+// line of the request.
+const maxRequestIDLength = 128
+
+// RequestID propagates the X-Request-ID header of the request, or generates
+// one, echoes it in the response and attaches it to the request context
+// and the Sentry scope. It must run after the Sentry middleware.
+func RequestID() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		id := ctx.GetHeader(RequestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+
+		ctx.Header(RequestIDHeader, id)
+		ctx.Request = ctx.Request.WithContext(logging.WithRequestID(ctx.Request.Context(), id))
+		if hub := sentrygin.GetHubFromContext(ctx); hub != nil {
+			hub.Scope().SetTag("request_id", id)
+		}
+
+		ctx.Next()
+	}
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, c := range []byte(id) {
+		if c < 0x21 || c > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,23 @@
+package model
+
+import "time"
+
+// Domain is a custom host that serves its own namespace of short names.
+type Domain struct {
+	ID        int64      `json:"id"`
+	Host      string     `json:"host"`
+	CreatedAt *time.Time `json:"created_at,omitempty"`
+}
+
+type DomainNotFoundError struct{}
+
+func (d *DomainNotFoundError) Error() string {
+	return "domain not found"
+}
+
+// DomainReservedError means the host is the default host of the shortener.
+type DomainReservedError struct{}
+
+func (d *DomainReservedError) Error() string {
+	return "host is already served by default"
+}
@@ -0,0 +1,23 @@
+package model
+
+const (
+	CheckStatusOK   = "ok"
+	CheckStatusFail = "fail"
+)
+
+// HealthCheck is the result of checking a single dependency. Details holds
+// check-specific information such as connection pool usage.
+type HealthCheck struct {
+	Status    string         `json:"status"`
+	Error     string         `json:"error,omitempty"`
+	LatencyMs *int64         `json:"latency_ms,omitempty"`
+	Details   map[string]any `json:"details,omitempty"`
+}
+
+// HealthReport is the body of the liveness and readiness endpoints.
+type HealthReport struct {
+	Status  string                 `json:"status"`
+	Version string                 `json:"version"`
+	Commit  string                 `json:"commit,omitempty"`
+	Checks  map[string]HealthCheck `json:"checks,omitempty"`
+}
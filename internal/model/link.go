@@ -1,10 +1,100 @@
 package model
 
+import (
+	"strings"
+	"time"
+)
+
 type Link struct {
-	ID          int64  `json:"id"`
-	OriginalUrl string `json:"original_url"`
-	ShortName   string `json:"short_name"`
-	ShortUrl    string `json:"short_url"`
+	ID          int64      `json:"id"`
+	OriginalUrl string     `json:"original_url"`
+	ShortName   string     `json:"short_name"`
+	ShortUrl    string     `json:"short_url"`
+	DomainID    *int64     `json:"domain_id,omitempty"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	MaxVisits   *int64     `json:"max_visits,omitempty"`
+	Expired     bool       `json:"expired"`
+	HasPassword bool       `json:"has_password"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+
+	PasswordHash string `json:"-"`
+}
+
+// IsExpired reports whether the link has been marked expired by the sweeper
+// or its expiration time has already passed. The max_visits limit depends on
+// the visits table and is checked separately.
+func (l *Link) IsExpired(now time.Time) bool {
+	return l.Expired || (l.ExpiresAt != nil && !now.Before(*l.ExpiresAt))
+}
+
+// reservedShortNames are paths served by the application or the frontend
+// at the root. They take precedence over root-path redirects, so they
+// cannot be used as short names.
+var reservedShortNames = map[string]struct{}{
+	"api":         {},
+	"r":           {},
+	"ping":        {},
+	"healthz":     {},
+	"readyz":      {},
+	"assets":      {},
+	"index.html":  {},
+	"favicon.ico": {},
+	"robots.txt":  {},
+}
+
+func IsReservedShortName(shortName string) bool {
+	_, ok := reservedShortNames[strings.ToLower(shortName)]
+	return ok
+}
+
+// LinkInput holds the user-editable fields of a link.
+type LinkInput struct {
+	OriginalUrl string
+	ShortName   string
+	// DomainID selects the custom domain of the link. nil means BASE_PATH.
+	DomainID  *int64
+	ExpiresAt *time.Time
+	MaxVisits *int64
+	// Generator names the short code strategy used when ShortName is
+	// empty. The deployment default is used when it is empty too.
+	Generator string
+	// Password is the plain-text password to protect the link with. A nil
+	// value keeps the current password on update, an empty one removes it.
+	Password *string
+}
+
+// LinkFilter narrows down the list of links. Broken selects links whose
+// destination was found broken (true) or healthy (false) by the last check.
+type LinkFilter struct {
+	Broken *bool
+	// Query matches a case-insensitive substring of the original URL or
+	// the short name.
+	Query string
+	IDs   []int64
+	// CreatedFrom is inclusive, CreatedTo exclusive.
+	CreatedFrom *time.Time
+	CreatedTo   *time.Time
+}
+
+// LinkExport is a link together with its visit count as written by the
+// export endpoint. Password hashes are intentionally not exported.
+type LinkExport struct {
+	ID          int64      `json:"id"`
+	OriginalUrl string     `json:"original_url"`
+	ShortName   string     `json:"short_name"`
+	ShortUrl    string     `json:"short_url"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	MaxVisits   *int64     `json:"max_visits,omitempty"`
+	Visits      int64      `json:"visits"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// BulkItemResult describes the outcome of a single item of a bulk request.
+type BulkItemResult struct {
+	Index  int               `json:"index"`
+	Link   *Link             `json:"link,omitempty"`
+	Errors map[string]string `json:"errors,omitempty"`
 }
 
 type LinkNotFoundError struct{}
@@ -12,3 +102,37 @@ type LinkNotFoundError struct{}
 func (l *LinkNotFoundError) Error() string {
 	return "not found"
 }
+
+type LinkExpiredError struct{}
+
+func (l *LinkExpiredError) Error() string {
+	return "link expired"
+}
+
+// BulkRejectedError means at least one item of a bulk request failed and
+// the whole batch was rolled back.
+type BulkRejectedError struct{}
+
+func (b *BulkRejectedError) Error() string {
+	return "bulk request rejected"
+}
+
+// DisallowedURLError means the destination URL was refused by the URL
+// policy. Reason is suitable for showing to the user.
+type DisallowedURLError struct {
+	Reason string
+}
+
+func (d *DisallowedURLError) Error() string {
+	return d.Reason
+}
+
+// InvalidParamsError means the query parameters of a request, such as a
+// list range, were rejected. Reason is suitable for showing to the user.
+type InvalidParamsError struct {
+	Reason string
+}
+
+func (i *InvalidParamsError) Error() string {
+	return i.Reason
+}
@@ -0,0 +1,20 @@
+package model
+
+import "time"
+
+const (
+	HealthStatusOK      = "ok"
+	HealthStatusBroken  = "broken"
+	HealthStatusUnknown = "unknown"
+)
+
+// LinkHealth is the result of the last check of a link's destination.
+// Status is HealthStatusUnknown until the link has been checked.
+type LinkHealth struct {
+	LinkID     int64      `json:"link_id"`
+	Status     string     `json:"status"`
+	StatusCode *int       `json:"status_code,omitempty"`
+	Error      string     `json:"error,omitempty"`
+	LatencyMs  *int64     `json:"latency_ms,omitempty"`
+	CheckedAt  *time.Time `json:"checked_at,omitempty"`
+}
@@ -0,0 +1,27 @@
+package model
+
+import "time"
+
+type LinkStats struct {
+	LinkID        int64         `json:"link_id"`
+	From          time.Time     `json:"from"`
+	To            time.Time     `json:"to"`
+	Interval      string        `json:"interval"`
+	Visits        int64         `json:"visits"`
+	UniqueIps     int64         `json:"unique_ips"`
+	Series        []StatsBucket `json:"series"`
+	TopReferers   []StatsCount  `json:"top_referers"`
+	TopUserAgents []StatsCount  `json:"top_user_agents"`
+	TopCountries  []StatsCount  `json:"top_countries"`
+}
+
+type StatsBucket struct {
+	Time      time.Time `json:"time"`
+	Visits    int64     `json:"visits"`
+	UniqueIps int64     `json:"unique_ips"`
+}
+
+type StatsCount struct {
+	Value  string `json:"value"`
+	Visits int64  `json:"visits"`
+}
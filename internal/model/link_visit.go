@@ -3,11 +3,36 @@ package model
 import "time"
 
 type LinkVisit struct {
-	ID        int64     `json:"id"`
-	LinkId    int64     `json:"link_id"`
-	Ip        string    `json:"ip"`
-	UserAgent *string   `json:"user_agent,omitempty"`
-	Referer   *string   `json:"referer,omitempty"`
-	Status    int64     `json:"status"`
-	CreatedAt time.Time `json:"created_at"`
+	ID             int64     `json:"id"`
+	LinkId         int64     `json:"link_id"`
+	Ip             string    `json:"ip"`
+	UserAgent      *string   `json:"user_agent,omitempty"`
+	Referer        *string   `json:"referer,omitempty"`
+	Status         int64     `json:"status"`
+	CreatedAt      time.Time `json:"created_at"`
+	Browser        string    `json:"browser"`
+	BrowserVersion string    `json:"browser_version"`
+	OS             string    `json:"os"`
+	DeviceType     string    `json:"device_type"`
+	IsBot          bool      `json:"is_bot"`
+	Country        string    `json:"country"`
+	Region         string    `json:"region"`
+	City           string    `json:"city"`
+	ASN            int64     `json:"asn,omitempty"`
+	ASOrg          string    `json:"as_org,omitempty"`
+}
+
+// VisitFilter narrows the visits list. Nil fields are not filtered on.
+type VisitFilter struct {
+	Browser    *string
+	OS         *string
+	DeviceType *string
+	IsBot      *bool
+	Country    *string
+	LinkID     *int64
+	IP         *string
+	Status     *int32
+	// CreatedFrom is inclusive, CreatedTo exclusive.
+	CreatedFrom *time.Time
+	CreatedTo   *time.Time
 }
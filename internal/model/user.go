@@ -0,0 +1,14 @@
+package model
+
+type User struct {
+	ID    int64  `json:"id"`
+	Email string `json:"email"`
+	// APIKeyID identifies the key the request was authenticated with.
+	APIKeyID int64 `json:"-"`
+}
+
+type UnauthorizedError struct{}
+
+func (u *UnauthorizedError) Error() string {
+	return "invalid or missing API key"
+}
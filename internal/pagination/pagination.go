@@ -0,0 +1,220 @@
+// Package pagination implements the query parameters of list endpoints:
+// keyset pagination with opaque cursors, and the react-admin sort and
+// filter parameters. For cursors clients pass ?limit=N for the first page
+// and ?after=<cursor>&limit=N for the following ones, and get the cursor of
+// the next page both in the body and in a Link header.
+package pagination
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	DefaultLimit = 50
+	MaxLimit     = 1000
+)
+
+// Cursor is the position of the last item of a page. Lists ordered by id
+// only use ID, lists ordered by time use CreatedAt with ID as tie-breaker.
+type Cursor struct {
+	CreatedAt time.Time
+	ID        int64
+}
+
+// Encode returns the opaque representation of c handed out to clients.
+func (c Cursor) Encode() string {
+	var nanos int64
+	if !c.CreatedAt.IsZero() {
+		nanos = c.CreatedAt.UnixNano()
+	}
+	raw := strconv.FormatInt(nanos, 10) + ":" + strconv.FormatInt(c.ID, 10)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// Decode parses a cursor produced by Encode.
+func Decode(s string) (Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return Cursor{}, errors.New("invalid cursor")
+	}
+
+	nanos, id, ok := strings.Cut(string(raw), ":")
+	if !ok {
+		return Cursor{}, errors.New("invalid cursor")
+	}
+
+	var c Cursor
+	n, err := strconv.ParseInt(nanos, 10, 64)
+	if err != nil {
+		return Cursor{}, errors.New("invalid cursor")
+	}
+	if n != 0 {
+		c.CreatedAt = time.Unix(0, n).UTC()
+	}
+	if c.ID, err = strconv.ParseInt(id, 10, 64); err != nil {
+		return Cursor{}, errors.New("invalid cursor")
+	}
+	return c, nil
+}
+
+// Params selects one page. After is nil for the first page.
+type Params struct {
+	After *Cursor
+	Limit int
+}
+
+// Page is the response body of a list endpoint in cursor mode. NextCursor
+// is nil on the last page.
+type Page[T any] struct {
+	Data       []T     `json:"data"`
+	NextCursor *string `json:"next_cursor"`
+}
+
+// FromQuery reads the after and limit query parameters. ok is false when
+// neither is present, in which case the endpoint keeps its range format.
+func FromQuery(ctx *gin.Context) (params Params, ok bool, err error) {
+	after, hasAfter := ctx.GetQuery("after")
+	limit, hasLimit := ctx.GetQuery("limit")
+	if !hasAfter && !hasLimit {
+		return Params{}, false, nil
+	}
+
+	params.Limit = DefaultLimit
+	if hasLimit {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 || n > MaxLimit {
+			return Params{}, true, fmt.Errorf("limit must be between 1 and %d", MaxLimit)
+		}
+		params.Limit = n
+	}
+
+	if hasAfter && after != "" {
+		c, err := Decode(after)
+		if err != nil {
+			return Params{}, true, err
+		}
+		params.After = &c
+	}
+	return params, true, nil
+}
+
+// Sort is the order requested with the react-admin sort parameter.
+type Sort struct {
+	Field string
+	Desc  bool
+}
+
+// ParseSort reads sort=["field","ASC"] or sort=["field","DESC"]. Only the
+// allowed fields are accepted, def is returned when the parameter is absent.
+func ParseSort(ctx *gin.Context, allowed []string, def Sort) (Sort, error) {
+	raw, ok := ctx.GetQuery("sort")
+	if !ok {
+		return def, nil
+	}
+
+	var parts []string
+	if err := json.Unmarshal([]byte(raw), &parts); err != nil || len(parts) != 2 {
+		return Sort{}, errors.New("wrong sort format")
+	}
+
+	if !slices.Contains(allowed, parts[0]) {
+		return Sort{}, fmt.Errorf("cannot sort by '%s'", parts[0])
+	}
+
+	switch strings.ToUpper(parts[1]) {
+	case "ASC":
+		return Sort{Field: parts[0]}, nil
+	case "DESC":
+		return Sort{Field: parts[0], Desc: true}, nil
+	default:
+		return Sort{}, errors.New("sort order must be ASC or DESC")
+	}
+}
+
+// OrderBy returns the ORDER BY clause for s. columns maps the sort fields
+// to the columns they order by and must contain "id", which breaks ties in
+// the same direction. Only mapped fields are accepted, so the clause never
+// contains user input.
+func (s Sort) OrderBy(columns map[string]string) (string, error) {
+	column, ok := columns[s.Field]
+	if !ok {
+		return "", fmt.Errorf("cannot sort by %q", s.Field)
+	}
+	direction := "ASC"
+	if s.Desc {
+		direction = "DESC"
+	}
+
+	clause := fmt.Sprintf("ORDER BY %s %s", column, direction)
+	if id := columns["id"]; column != id {
+		clause += fmt.Sprintf(", %s %s", id, direction)
+	}
+	return clause, nil
+}
+
+// ParseFilter decodes the react-admin filter={...} parameter into dst.
+// Unknown fields are rejected so that typos do not silently list everything.
+func ParseFilter(ctx *gin.Context, dst any) error {
+	raw, ok := ctx.GetQuery("filter")
+	if !ok || raw == "" {
+		return nil
+	}
+
+	dec := json.NewDecoder(strings.NewReader(raw))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(dst); err != nil {
+		return errors.New("wrong filter format")
+	}
+	return nil
+}
+
+// ParseDate parses a date filter given either as RFC 3339 or as a plain
+// date, as sent by react-admin date inputs. With endOfDay a plain date is
+// moved to the start of the next day, for use as an exclusive upper bound.
+func ParseDate(s string, endOfDay bool) (*time.Time, error) {
+	if s == "" {
+		return nil, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		t = t.UTC()
+		return &t, nil
+	}
+
+	t, err := time.Parse(time.DateOnly, s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid date '%s'", s)
+	}
+	if endOfDay {
+		t = t.AddDate(0, 0, 1)
+	}
+	return &t, nil
+}
+
+// Respond writes items as a Page. When next is set, it is also advertised
+// in a Link header pointing at the same URL with the after parameter
+// replaced.
+func Respond[T any](ctx *gin.Context, items []T, next *Cursor, limit int) {
+	page := Page[T]{Data: items}
+	if next != nil {
+		cursor := next.Encode()
+		page.NextCursor = &cursor
+
+		u := *ctx.Request.URL
+		query := u.Query()
+		query.Set("after", cursor)
+		query.Set("limit", strconv.Itoa(limit))
+		u.RawQuery = query.Encode()
+		ctx.Header("Link", fmt.Sprintf(`<%s>; rel="next"`, u.RequestURI()))
+	}
+	ctx.JSON(http.StatusOK, page)
+}
@@ -0,0 +1,32 @@
+package pagination
+
+import "testing"
+
+func TestSortOrderBy(t *testing.T) {
+	columns := map[string]string{
+		"id":         "link_visits.id",
+		"created_at": "link_visits.created_at",
+		"country":    "link_visits.country",
+	}
+	tests := []struct {
+		sort    Sort
+		want    string
+		wantErr bool
+	}{
+		{sort: Sort{Field: "id"}, want: "ORDER BY link_visits.id ASC"},
+		{sort: Sort{Field: "created_at", Desc: true}, want: "ORDER BY link_visits.created_at DESC, link_visits.id DESC"},
+		{sort: Sort{Field: "country"}, want: "ORDER BY link_visits.country ASC, link_visits.id ASC"},
+		{sort: Sort{Field: "id; DROP TABLE links"}, wantErr: true},
+		{sort: Sort{}, wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := tt.sort.OrderBy(columns)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%+v.OrderBy() error = %v, want error %t", tt.sort, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%+v.OrderBy() = %q, want %q", tt.sort, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// pruneInterval is how often idle, full buckets are dropped so the map does
+// not grow with every client ever seen.
+const pruneInterval = time.Minute
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	policy  Policy
+}
+
+type memoryLimiter struct {
+	mu         sync.Mutex
+	buckets    map[string]*bucket
+	lastPruned time.Time
+	now        func() time.Time
+}
+
+// NewMemoryLimiter returns a Limiter that keeps buckets in process. It is
+// used when Redis is not configured, so limits apply per replica.
+func NewMemoryLimiter() *memoryLimiter {
+	return &memoryLimiter{
+		buckets: make(map[string]*bucket),
+		now:     time.Now,
+	}
+}
+
+func (l *memoryLimiter) Allow(_ context.Context, key string, policy Policy, cost int) (Result, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	if now.Sub(l.lastPruned) >= pruneInterval {
+		l.prune(now)
+		l.lastPruned = now
+	}
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(policy.Burst), updated: now, policy: policy}
+		l.buckets[key] = b
+	}
+	b.refill(now)
+
+	needed := float64(min(cost, policy.Burst))
+	res := Result{Limit: policy.Burst}
+	if b.tokens >= needed {
+		b.tokens -= float64(cost)
+		res.Allowed = true
+	} else {
+		res.RetryAfter = secondsToDuration((needed - b.tokens) / policy.Rate)
+	}
+	res.Remaining = max(int(math.Floor(b.tokens)), 0)
+	res.ResetAfter = secondsToDuration((float64(policy.Burst) - b.tokens) / policy.Rate)
+
+	return res, nil
+}
+
+func (b *bucket) refill(now time.Time) {
+	elapsed := now.Sub(b.updated).Seconds()
+	if elapsed > 0 {
+		b.tokens = math.Min(float64(b.policy.Burst), b.tokens+elapsed*b.policy.Rate)
+	}
+	b.updated = now
+}
+
+// prune drops buckets that have refilled completely, since a new bucket
+// would start in the same state.
+func (l *memoryLimiter) prune(now time.Time) {
+	for key, b := range l.buckets {
+		b.refill(now)
+		if b.tokens >= float64(b.policy.Burst) {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+func secondsToDuration(seconds float64) time.Duration {
+	return time.Duration(math.Ceil(seconds * float64(time.Second)))
+}
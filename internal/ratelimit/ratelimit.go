@@ -0,0 +1,36 @@
+// Package ratelimit implements token-bucket rate limiting.
+package ratelimit
+
+import (
+	"context"
+	"time"
+)
+
+// Policy describes a token bucket: Burst tokens at most, refilled at Rate
+// tokens per second.
+type Policy struct {
+	Rate  float64
+	Burst int
+}
+
+// Result is the outcome of a single Allow call.
+type Result struct {
+	Allowed bool
+	Limit   int
+	// Remaining is the number of whole tokens left after this call.
+	Remaining int
+	// RetryAfter is how long until the next request would be allowed. It is
+	// zero when the request was allowed.
+	RetryAfter time.Duration
+	// ResetAfter is how long until the bucket is full again.
+	ResetAfter time.Duration
+}
+
+// Limiter takes cost tokens for key from the bucket described by policy.
+//
+// A cost above Burst could never be paid in one go, so such a request is
+// allowed once the bucket is full and leaves it in debt: later requests
+// wait until the debt has been refilled.
+type Limiter interface {
+	Allow(ctx context.Context, key string, policy Policy, cost int) (Result, error)
+}
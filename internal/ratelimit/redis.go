@@ -0,0 +1,78 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const redisKeyPrefix = "ratelimit:"
+
+// tokenBucketScript refills and takes from a bucket atomically. The Redis
+// server clock is used so replicas with skewed clocks share one bucket.
+//
+// KEYS[1] bucket key; ARGV[1] rate per second; ARGV[2] burst; ARGV[3] cost.
+// Returns {allowed, remaining tokens, retry after ms, reset after ms}.
+var tokenBucketScript = redis.NewScript(`
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local cost = tonumber(ARGV[3])
+local needed = math.min(cost, burst)
+
+local time = redis.call('TIME')
+local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(state[1])
+local ts = tonumber(state[2])
+if tokens == nil then
+	tokens = burst
+	ts = now
+end
+
+tokens = math.min(burst, tokens + math.max(0, now - ts) * rate / 1000)
+
+local allowed = 0
+local retry = 0
+if tokens >= needed then
+	tokens = tokens - cost
+	allowed = 1
+else
+	retry = math.ceil((needed - tokens) * 1000 / rate)
+end
+
+local reset = math.ceil((burst - tokens) * 1000 / rate)
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', now)
+redis.call('PEXPIRE', KEYS[1], math.max(reset, 1000))
+
+return {allowed, math.floor(tokens), retry, reset}
+`)
+
+type redisLimiter struct {
+	client *redis.Client
+}
+
+// NewRedisLimiter returns a Limiter whose buckets live in Redis and are
+// shared by every replica.
+func NewRedisLimiter(client *redis.Client) *redisLimiter {
+	return &redisLimiter{client: client}
+}
+
+func (l *redisLimiter) Allow(ctx context.Context, key string, policy Policy, cost int) (Result, error) {
+	res, err := tokenBucketScript.Run(ctx, l.client, []string{redisKeyPrefix + key},
+		strconv.FormatFloat(policy.Rate, 'f', -1, 64), policy.Burst, cost).Int64Slice()
+	if err != nil {
+		return Result{}, err
+	}
+
+	return Result{
+		Allowed:    res[0] == 1,
+		Limit:      policy.Burst,
+		Remaining:  int(math.Max(0, float64(res[1]))),
+		RetryAfter: time.Duration(res[2]) * time.Millisecond,
+		ResetAfter: time.Duration(res[3]) * time.Millisecond,
+	}, nil
+}
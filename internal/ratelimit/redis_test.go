@@ -0,0 +1,157 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func newTestRedis(t *testing.T) (*miniredis.Miniredis, *redis.Client) {
+	t.Helper()
+
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+	return mr, client
+}
+
+func TestRedisLimiterTakesTokensUntilEmpty(t *testing.T) {
+	mr, client := newTestRedis(t)
+	mr.SetTime(time.Unix(1700000000, 0))
+	limiter := NewRedisLimiter(client)
+	policy := Policy{Rate: 1, Burst: 3}
+
+	for want := 2; want >= 0; want-- {
+		res, err := limiter.Allow(context.Background(), "client", policy, 1)
+		if err != nil {
+			t.Fatalf("Allow: %v", err)
+		}
+		if !res.Allowed || res.Remaining != want || res.Limit != 3 {
+			t.Fatalf("Allow = %+v, want allowed with %d remaining", res, want)
+		}
+		if res.RetryAfter != 0 {
+			t.Errorf("RetryAfter = %s for an allowed request", res.RetryAfter)
+		}
+	}
+
+	res, err := limiter.Allow(context.Background(), "client", policy, 1)
+	if err != nil {
+		t.Fatalf("Allow: %v", err)
+	}
+	if res.Allowed {
+		t.Fatalf("Allow on an empty bucket = %+v, want denied", res)
+	}
+	if res.RetryAfter != time.Second {
+		t.Errorf("RetryAfter = %s, want 1s", res.RetryAfter)
+	}
+	if res.ResetAfter != 3*time.Second {
+		t.Errorf("ResetAfter = %s, want 3s", res.ResetAfter)
+	}
+	if ttl := mr.TTL(redisKeyPrefix + "client"); ttl <= 0 {
+		t.Errorf("bucket key has no expiry, ttl = %s", ttl)
+	}
+}
+
+func TestRedisLimiterRefillsOverTime(t *testing.T) {
+	mr, client := newTestRedis(t)
+	start := time.Unix(1700000000, 0)
+	mr.SetTime(start)
+	limiter := NewRedisLimiter(client)
+	policy := Policy{Rate: 2, Burst: 1}
+
+	if res, err := limiter.Allow(context.Background(), "client", policy, 1); err != nil || !res.Allowed {
+		t.Fatalf("first Allow = %+v, %v", res, err)
+	}
+	if res, err := limiter.Allow(context.Background(), "client", policy, 1); err != nil || res.Allowed {
+		t.Fatalf("second Allow = %+v, %v, want denied", res, err)
+	}
+
+	// Two tokens per second refill the single token in half a second.
+	mr.SetTime(start.Add(500 * time.Millisecond))
+	if res, err := limiter.Allow(context.Background(), "client", policy, 1); err != nil || !res.Allowed {
+		t.Fatalf("Allow after refill = %+v, %v", res, err)
+	}
+}
+
+func TestRedisLimiterSeparatesKeys(t *testing.T) {
+	mr, client := newTestRedis(t)
+	mr.SetTime(time.Unix(1700000000, 0))
+	limiter := NewRedisLimiter(client)
+	policy := Policy{Rate: 1, Burst: 1}
+
+	for _, key := range []string{"a", "b"} {
+		res, err := limiter.Allow(context.Background(), key, policy, 1)
+		if err != nil || !res.Allowed {
+			t.Fatalf("Allow(%q) = %+v, %v", key, res, err)
+		}
+	}
+}
+
+func TestRedisLimiterSharesBucketsBetweenReplicas(t *testing.T) {
+	mr, client := newTestRedis(t)
+	mr.SetTime(time.Unix(1700000000, 0))
+	other := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = other.Close() })
+	policy := Policy{Rate: 1, Burst: 1}
+
+	if res, err := NewRedisLimiter(client).Allow(context.Background(), "client", policy, 1); err != nil || !res.Allowed {
+		t.Fatalf("Allow on the first replica = %+v, %v", res, err)
+	}
+	if res, err := NewRedisLimiter(other).Allow(context.Background(), "client", policy, 1); err != nil || res.Allowed {
+		t.Fatalf("Allow on the second replica = %+v, %v, want denied", res, err)
+	}
+}
+
+func TestRedisLimiterReportsRedisErrors(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr(), MaxRetries: -1})
+	t.Cleanup(func() { _ = client.Close() })
+	mr.Close()
+
+	if _, err := NewRedisLimiter(client).Allow(context.Background(), "client", Policy{Rate: 1, Burst: 1}, 1); err == nil {
+		t.Fatal("Allow succeeded with Redis down")
+	}
+}
+
+func TestRedisLimiterChargesCost(t *testing.T) {
+	mr, client := newTestRedis(t)
+	start := time.Unix(1700000000, 0)
+	mr.SetTime(start)
+	limiter := NewRedisLimiter(client)
+	policy := Policy{Rate: 1, Burst: 3}
+
+	res, err := limiter.Allow(context.Background(), "client", policy, 2)
+	if err != nil || !res.Allowed || res.Remaining != 1 {
+		t.Fatalf("Allow(cost 2) = %+v, %v, want allowed with 1 remaining", res, err)
+	}
+
+	// A cost above the burst waits for a full bucket.
+	res, err = limiter.Allow(context.Background(), "client", policy, 5)
+	if err != nil || res.Allowed {
+		t.Fatalf("Allow(cost 5) = %+v, %v, want denied", res, err)
+	}
+	if res.RetryAfter != 2*time.Second {
+		t.Errorf("RetryAfter = %s, want 2s", res.RetryAfter)
+	}
+
+	mr.SetTime(start.Add(2 * time.Second))
+	res, err = limiter.Allow(context.Background(), "client", policy, 5)
+	if err != nil || !res.Allowed || res.Remaining != 0 {
+		t.Fatalf("Allow(cost 5) on a full bucket = %+v, %v, want allowed with 0 remaining", res, err)
+	}
+	if res.ResetAfter != 5*time.Second {
+		t.Errorf("ResetAfter = %s, want 5s", res.ResetAfter)
+	}
+
+	// The bucket is now two tokens in debt.
+	res, err = limiter.Allow(context.Background(), "client", policy, 1)
+	if err != nil || res.Allowed {
+		t.Fatalf("Allow in debt = %+v, %v, want denied", res, err)
+	}
+	if res.RetryAfter != 3*time.Second {
+		t.Errorf("RetryAfter = %s, want 3s", res.RetryAfter)
+	}
+}
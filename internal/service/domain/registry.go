@@ -0,0 +1,169 @@
+package domain
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"markoni23/url-shortener/internal/model"
+	"markoni23/url-shortener/internal/sqlcdb"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// reloadInterval limits how often an unknown Host header can trigger a
+// reload, so random hosts cannot be used to hammer the database.
+const reloadInterval = 10 * time.Second
+
+// Registry keeps every custom domain in memory. It reloads when it sees an
+// id or host it does not know yet, e.g. a domain created on another
+// replica, and periodically through RunRefresher, so that a domain deleted
+// or re-created elsewhere does not keep resolving to its old id. Concurrent
+// reloads are collapsed into one query.
+type Registry struct {
+	queries  *sqlcdb.Queries
+	baseHost string
+	now      func() time.Time
+	group    singleflight.Group
+
+	mu          sync.RWMutex
+	byID        map[int64]string
+	byHost      map[string]int64
+	attemptedAt time.Time
+}
+
+// NewRegistry returns an empty registry. Requests for the host of basePath
+// never trigger a reload, since it cannot be a custom domain.
+func NewRegistry(basePath string, queries *sqlcdb.Queries) *Registry {
+	var baseHost string
+	if u, err := url.Parse(basePath); err == nil {
+		baseHost = NormalizeHost(u.Host)
+	}
+
+	return &Registry{
+		queries:  queries,
+		baseHost: baseHost,
+		now:      time.Now,
+		byID:     make(map[int64]string),
+		byHost:   make(map[string]int64),
+	}
+}
+
+// Host returns the host of the domain with the given id.
+func (r *Registry) Host(ctx context.Context, id int64) (string, error) {
+	if host, ok := r.host(id); ok {
+		return host, nil
+	}
+
+	if err := r.Reload(ctx); err != nil {
+		return "", err
+	}
+	if host, ok := r.host(id); ok {
+		return host, nil
+	}
+	return "", &model.DomainNotFoundError{}
+}
+
+// Lookup returns the id of the domain served at host. host may contain a
+// port, as in a Host header. If the reload for an unknown host fails, the
+// domains loaded before are used.
+func (r *Registry) Lookup(ctx context.Context, host string) (int64, bool, error) {
+	host = NormalizeHost(host)
+	if host == r.baseHost {
+		return 0, false, nil
+	}
+	if id, ok := r.lookup(host); ok {
+		return id, true, nil
+	}
+
+	r.mu.RLock()
+	stale := r.now().Sub(r.attemptedAt) >= reloadInterval
+	r.mu.RUnlock()
+	if !stale {
+		return 0, false, nil
+	}
+
+	if err := r.Reload(ctx); err != nil {
+		slog.WarnContext(ctx, "failed to reload domains, using the loaded ones", "error", err)
+	}
+	id, ok := r.lookup(host)
+	return id, ok, nil
+}
+
+// Reload replaces the registry content with the domains in the database.
+// The content is kept when the query fails.
+func (r *Registry) Reload(ctx context.Context) error {
+	_, err, _ := r.group.Do("reload", func() (any, error) {
+		return nil, r.reload(ctx)
+	})
+	return err
+}
+
+// RunRefresher reloads the registry every interval. It blocks until ctx is
+// cancelled.
+func (r *Registry) RunRefresher(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := r.Reload(ctx); err != nil {
+				slog.ErrorContext(ctx, "failed to refresh domains", "error", err)
+			}
+		}
+	}
+}
+
+func (r *Registry) reload(ctx context.Context) error {
+	r.mu.Lock()
+	r.attemptedAt = r.now()
+	r.mu.Unlock()
+
+	rows, err := r.queries.GetDomains(ctx)
+	if err != nil {
+		return err
+	}
+
+	byID := make(map[int64]string, len(rows))
+	byHost := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		byID[row.ID] = row.Host
+		byHost[row.Host] = row.ID
+	}
+
+	r.mu.Lock()
+	r.byID = byID
+	r.byHost = byHost
+	r.mu.Unlock()
+
+	return nil
+}
+
+func (r *Registry) host(id int64) (string, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	host, ok := r.byID[id]
+	return host, ok
+}
+
+func (r *Registry) lookup(host string) (int64, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	id, ok := r.byHost[host]
+	return id, ok
+}
+
+// NormalizeHost lowercases host and strips the port and trailing dot.
+func NormalizeHost(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(host)), ".")
+}
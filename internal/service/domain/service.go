@@ -0,0 +1,112 @@
+package domain
+
+import (
+	"context"
+	"log/slog"
+	"net/url"
+
+	"markoni23/url-shortener/internal/model"
+	"markoni23/url-shortener/internal/sqlcdb"
+)
+
+// LinkCache drops cached short links, so that the links deleted together
+// with their domain stop redirecting.
+type LinkCache interface {
+	InvalidateDomainLinks(ctx context.Context, domainID int64, shortNames []string)
+}
+
+type service struct {
+	baseHost  string
+	queries   *sqlcdb.Queries
+	registry  *Registry
+	linkCache LinkCache
+}
+
+func NewService(basePath string, queries *sqlcdb.Queries, registry *Registry, linkCache LinkCache) *service {
+	var baseHost string
+	if u, err := url.Parse(basePath); err == nil {
+		baseHost = NormalizeHost(u.Host)
+	}
+
+	return &service{
+		baseHost:  baseHost,
+		queries:   queries,
+		registry:  registry,
+		linkCache: linkCache,
+	}
+}
+
+func (s *service) GetAll(ctx context.Context, ownerID int64) ([]model.Domain, error) {
+	rows, err := s.queries.GetDomainsByOwner(ctx, ownerID)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]model.Domain, len(rows))
+	for i, row := range rows {
+		res[i] = rawToModel(row)
+	}
+	return res, nil
+}
+
+func (s *service) Create(ctx context.Context, ownerID int64, host string) (model.Domain, error) {
+	host = NormalizeHost(host)
+	if host == s.baseHost {
+		return model.Domain{}, &model.DomainReservedError{}
+	}
+
+	row, err := s.queries.CreateDomain(ctx, sqlcdb.CreateDomainParams{
+		Host:    host,
+		OwnerID: ownerID,
+	})
+	if err != nil {
+		return model.Domain{}, err
+	}
+
+	s.reload(ctx)
+	return rawToModel(row), nil
+}
+
+// Delete removes the domain together with all of its links.
+func (s *service) Delete(ctx context.Context, ownerID, id int64) error {
+	rows, err := s.queries.DeleteDomain(ctx, sqlcdb.DeleteDomainParams{
+		ID:      id,
+		OwnerID: ownerID,
+	})
+	if err != nil {
+		return err
+	}
+	if len(rows) == 0 {
+		return &model.DomainNotFoundError{}
+	}
+
+	// The links are deleted by the foreign key, so their cache entries
+	// have to be dropped here.
+	var shortNames []string
+	for _, row := range rows {
+		if row.ShortName.Valid {
+			shortNames = append(shortNames, row.ShortName.String)
+		}
+	}
+	s.linkCache.InvalidateDomainLinks(ctx, id, shortNames)
+
+	s.reload(ctx)
+	return nil
+}
+
+func (s *service) reload(ctx context.Context) {
+	if err := s.registry.Reload(ctx); err != nil {
+		slog.ErrorContext(ctx, "failed to reload domains", "error", err)
+	}
+}
+
+func rawToModel(raw sqlcdb.Domain) model.Domain {
+	domain := model.Domain{
+		ID:   raw.ID,
+		Host: raw.Host,
+	}
+	if raw.CreatedAt.Valid {
+		domain.CreatedAt = &raw.CreatedAt.Time
+	}
+	return domain
+}
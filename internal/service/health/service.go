@@ -0,0 +1,178 @@
+package health
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"slices"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"markoni23/url-shortener/internal/model"
+)
+
+// pingTimeout bounds the database check, so that a hanging connection makes
+// the probe fail instead of time out.
+const pingTimeout = 2 * time.Second
+
+var migrationFileRe = regexp.MustCompile(`^(\d+)_.+\.sql$`)
+
+type service struct {
+	db       *sql.DB
+	versions []int64
+	// migrated is set once all embedded migrations were found applied, so
+	// that later probes skip the query.
+	migrated atomic.Bool
+}
+
+// NewService reads the migration versions from the goose migration files
+// in migrations.
+func NewService(db *sql.DB, migrations fs.FS) (*service, error) {
+	entries, err := fs.ReadDir(migrations, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	var versions []int64
+	for _, entry := range entries {
+		m := migrationFileRe.FindStringSubmatch(entry.Name())
+		if m == nil {
+			continue
+		}
+		version, err := strconv.ParseInt(m[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration %s: %w", entry.Name(), err)
+		}
+		versions = append(versions, version)
+	}
+	slices.Sort(versions)
+
+	return &service{
+		db:       db,
+		versions: versions,
+	}, nil
+}
+
+// Checks runs the readiness checks of the dependencies.
+func (s *service) Checks(ctx context.Context) map[string]model.HealthCheck {
+	checks := map[string]model.HealthCheck{
+		"database": s.checkDatabase(ctx),
+	}
+	if checks["database"].Status == model.CheckStatusOK {
+		checks["migrations"] = s.checkMigrations(ctx)
+	}
+	return checks
+}
+
+func (s *service) checkDatabase(ctx context.Context) model.HealthCheck {
+	ctx, cancel := context.WithTimeout(ctx, pingTimeout)
+	defer cancel()
+
+	start := time.Now()
+	err := s.db.PingContext(ctx)
+	latency := time.Since(start).Milliseconds()
+
+	stats := s.db.Stats()
+	check := model.HealthCheck{
+		Status:    model.CheckStatusOK,
+		LatencyMs: &latency,
+		Details: map[string]any{
+			"max_open":      stats.MaxOpenConnections,
+			"open":          stats.OpenConnections,
+			"in_use":        stats.InUse,
+			"idle":          stats.Idle,
+			"wait_count":    stats.WaitCount,
+			"wait_duration": stats.WaitDuration.String(),
+		},
+	}
+	if err != nil {
+		check.Status = model.CheckStatusFail
+		check.Error = err.Error()
+	}
+	return check
+}
+
+// checkMigrations fails while any migration embedded in the binary is not
+// applied. Migrations newer than the binary, as during a rolling deploy,
+// are fine.
+func (s *service) checkMigrations(ctx context.Context) model.HealthCheck {
+	var expected int64
+	if len(s.versions) > 0 {
+		expected = s.versions[len(s.versions)-1]
+	}
+	if s.migrated.Load() {
+		return model.HealthCheck{
+			Status:  model.CheckStatusOK,
+			Details: map[string]any{"expected": expected},
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, pingTimeout)
+	defer cancel()
+
+	applied, err := s.appliedVersions(ctx)
+	if err != nil {
+		return model.HealthCheck{
+			Status: model.CheckStatusFail,
+			Error:  err.Error(),
+		}
+	}
+
+	var current int64
+	for version := range applied {
+		current = max(current, version)
+	}
+
+	var pending []int64
+	for _, version := range s.versions {
+		if !applied[version] {
+			pending = append(pending, version)
+		}
+	}
+
+	check := model.HealthCheck{
+		Status: model.CheckStatusOK,
+		Details: map[string]any{
+			"current":  current,
+			"expected": expected,
+		},
+	}
+	if len(pending) > 0 {
+		check.Status = model.CheckStatusFail
+		check.Error = fmt.Sprintf("%d pending migrations", len(pending))
+		check.Details["pending"] = pending
+		return check
+	}
+
+	s.migrated.Store(true)
+	return check
+}
+
+// appliedVersions reads the goose version table. A version counts as
+// applied when its latest entry is an up migration.
+func (s *service) appliedVersions(ctx context.Context) (map[int64]bool, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT DISTINCT ON (version_id) version_id, is_applied
+		FROM goose_db_version
+		ORDER BY version_id, id DESC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[int64]bool)
+	for rows.Next() {
+		var version int64
+		var isApplied bool
+		if err := rows.Scan(&version, &isApplied); err != nil {
+			return nil, err
+		}
+		if isApplied {
+			applied[version] = true
+		}
+	}
+	return applied, rows.Err()
+}
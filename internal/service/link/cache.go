@@ -0,0 +1,267 @@
+package link
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"markoni23/url-shortener/internal/cache"
+	"markoni23/url-shortener/internal/config"
+	"markoni23/url-shortener/internal/model"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	redisKeyPrefix          = "links:short_name:"
+	redisInvalidateChannel  = "links:invalidate"
+	redisOperationTimeout   = 200 * time.Millisecond
+	invalidationRetryPeriod = 5 * time.Second
+)
+
+type cachedLink struct {
+	link  model.Link
+	found bool
+}
+
+// redisEntry is the Redis representation of cachedLink. The password hash
+// is stored explicitly because model.Link does not serialize it.
+type redisEntry struct {
+	Link         model.Link `json:"link"`
+	PasswordHash string     `json:"password_hash,omitempty"`
+	Found        bool       `json:"found"`
+}
+
+// LinkCache keeps recently resolved short names in memory and, when Redis
+// is configured, in a cache shared by all replicas. Unknown short names are
+// cached too, with a shorter TTL, so that scans for random codes do not
+// reach the database. Invalidations are published over Redis pub/sub so
+// every replica drops its local copy. A nil *LinkCache disables caching.
+type LinkCache struct {
+	lru         *cache.LRU[string, cachedLink]
+	redis       *redis.Client
+	ttl         time.Duration
+	negativeTTL time.Duration
+}
+
+// NewLinkCache returns nil when neither the local cache nor Redis is
+// enabled. client may be nil.
+func NewLinkCache(cfg config.LinkCacheConfig, client *redis.Client) *LinkCache {
+	if cfg.Size <= 0 && client == nil {
+		return nil
+	}
+
+	c := &LinkCache{
+		redis:       client,
+		ttl:         cfg.TTL,
+		negativeTTL: cfg.NegativeTTL,
+	}
+	if cfg.Size > 0 {
+		c.lru = cache.NewLRU[string, cachedLink](cfg.Size)
+	}
+	return c
+}
+
+// cacheKey identifies a short name within the namespace of a domain. The
+// default namespace of BASE_PATH uses domain id 0. Short names are unique
+// regardless of case, so the key is lowercased.
+func cacheKey(domainID int64, shortName string) string {
+	return strconv.FormatInt(domainID, 10) + "/" + strings.ToLower(shortName)
+}
+
+func linkCacheKey(domainID *int64, shortName string) string {
+	if domainID == nil {
+		return cacheKey(0, shortName)
+	}
+	return cacheKey(*domainID, shortName)
+}
+
+// get reports the cached link and whether it exists. ok is false on a
+// cache miss. Redis errors are logged and treated as misses so that lookups
+// fall back to Postgres.
+func (c *LinkCache) get(ctx context.Context, key string) (link model.Link, found, ok bool) {
+	if c == nil {
+		return model.Link{}, false, false
+	}
+
+	if c.lru != nil {
+		if entry, ok := c.lru.Get(key); ok {
+			return entry.link, entry.found, true
+		}
+	}
+
+	if c.redis == nil {
+		return model.Link{}, false, false
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, redisOperationTimeout)
+	defer cancel()
+
+	raw, err := c.redis.Get(ctx, redisKeyPrefix+key).Bytes()
+	if err != nil {
+		if !errors.Is(err, redis.Nil) {
+			slog.WarnContext(ctx, "link cache redis get failed", "error", err)
+		}
+		return model.Link{}, false, false
+	}
+
+	var entry redisEntry
+	if err := json.Unmarshal(raw, &entry); err != nil {
+		slog.WarnContext(ctx, "link cache decode failed", "key", key, "error", err)
+		return model.Link{}, false, false
+	}
+	entry.Link.PasswordHash = entry.PasswordHash
+
+	ttl := c.ttl
+	if !entry.Found {
+		ttl = c.negativeTTL
+	}
+	c.setLocal(key, cachedLink{link: entry.Link, found: entry.Found}, ttl)
+
+	return entry.Link, entry.Found, true
+}
+
+func (c *LinkCache) set(ctx context.Context, key string, link model.Link) {
+	if c == nil {
+		return
+	}
+	c.setLocal(key, cachedLink{link: link, found: true}, c.ttl)
+	c.setShared(ctx, key, redisEntry{Link: link, PasswordHash: link.PasswordHash, Found: true}, c.ttl)
+}
+
+func (c *LinkCache) setNotFound(ctx context.Context, key string) {
+	if c == nil {
+		return
+	}
+	c.setLocal(key, cachedLink{}, c.negativeTTL)
+	c.setShared(ctx, key, redisEntry{}, c.negativeTTL)
+}
+
+func (c *LinkCache) invalidate(ctx context.Context, keys ...string) {
+	if c == nil || len(keys) == 0 {
+		return
+	}
+
+	if c.lru != nil {
+		for _, key := range keys {
+			c.lru.Delete(key)
+		}
+	}
+
+	if c.redis == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), redisOperationTimeout)
+	defer cancel()
+
+	redisKeys := make([]string, len(keys))
+	for i, key := range keys {
+		redisKeys[i] = redisKeyPrefix + key
+	}
+	if err := c.redis.Del(ctx, redisKeys...).Err(); err != nil {
+		slog.WarnContext(ctx, "link cache redis del failed", "error", err)
+	}
+
+	payload, _ := json.Marshal(keys)
+	if err := c.redis.Publish(ctx, redisInvalidateChannel, payload).Err(); err != nil {
+		slog.WarnContext(ctx, "link cache invalidation publish failed", "error", err)
+	}
+}
+
+// InvalidateDomainLinks drops the given short names of a domain, e.g. after
+// the domain and its links were deleted.
+func (c *LinkCache) InvalidateDomainLinks(ctx context.Context, domainID int64, shortNames []string) {
+	keys := make([]string, len(shortNames))
+	for i, name := range shortNames {
+		keys[i] = cacheKey(domainID, name)
+	}
+	c.invalidate(ctx, keys...)
+}
+
+// RunInvalidationListener drops local entries invalidated by other
+// replicas. It blocks until ctx is cancelled and is a no-op without Redis.
+func (c *LinkCache) RunInvalidationListener(ctx context.Context) {
+	if c == nil || c.redis == nil || c.lru == nil {
+		return
+	}
+
+	for ctx.Err() == nil {
+		c.listen(ctx)
+
+		select {
+		case <-ctx.Done():
+		case <-time.After(invalidationRetryPeriod):
+		}
+	}
+}
+
+func (c *LinkCache) listen(ctx context.Context) {
+	sub := c.redis.Subscribe(ctx, redisInvalidateChannel)
+	defer func() {
+		_ = sub.Close()
+	}()
+
+	if _, err := sub.Receive(ctx); err != nil {
+		if ctx.Err() == nil {
+			slog.ErrorContext(ctx, "link cache subscribe failed", "error", err)
+		}
+		return
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg, ok := <-sub.Channel():
+			if !ok {
+				return
+			}
+
+			var keys []string
+			if err := json.Unmarshal([]byte(msg.Payload), &keys); err != nil {
+				slog.WarnContext(ctx, "link cache received bad invalidation message", "error", err)
+				continue
+			}
+			for _, key := range keys {
+				c.lru.Delete(key)
+			}
+		}
+	}
+}
+
+func (c *LinkCache) Stats() cache.Stats {
+	if c == nil || c.lru == nil {
+		return cache.Stats{}
+	}
+	return c.lru.Stats()
+}
+
+func (c *LinkCache) setLocal(key string, entry cachedLink, ttl time.Duration) {
+	if c.lru != nil {
+		c.lru.Set(key, entry, ttl)
+	}
+}
+
+func (c *LinkCache) setShared(ctx context.Context, key string, entry redisEntry, ttl time.Duration) {
+	if c.redis == nil {
+		return
+	}
+
+	payload, err := json.Marshal(entry)
+	if err != nil {
+		slog.WarnContext(ctx, "link cache encode failed", "key", key, "error", err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, redisOperationTimeout)
+	defer cancel()
+
+	if err := c.redis.Set(ctx, redisKeyPrefix+key, payload, ttl).Err(); err != nil {
+		slog.WarnContext(ctx, "link cache redis set failed", "error", err)
+	}
+}
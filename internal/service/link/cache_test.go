@@ -0,0 +1,125 @@
+package link
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"markoni23/url-shortener/internal/config"
+	"markoni23/url-shortener/internal/model"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func newTestCache(t *testing.T, mr *miniredis.Miniredis) *LinkCache {
+	t.Helper()
+
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr(), MaxRetries: -1})
+	t.Cleanup(func() { _ = client.Close() })
+	return NewLinkCache(config.LinkCacheConfig{
+		Size:        100,
+		TTL:         time.Minute,
+		NegativeTTL: time.Second,
+	}, client)
+}
+
+func TestLinkCacheSharesEntriesBetweenReplicas(t *testing.T) {
+	mr := miniredis.RunT(t)
+	a, b := newTestCache(t, mr), newTestCache(t, mr)
+	ctx := context.Background()
+	key := cacheKey(0, "Promo")
+
+	link := model.Link{ID: 1, OriginalUrl: "https://example.com", ShortName: "Promo", PasswordHash: "hash"}
+	a.set(ctx, key, link)
+
+	got, found, ok := b.get(ctx, cacheKey(0, "promo"))
+	if !ok || !found {
+		t.Fatalf("get on the other replica = found %t, ok %t, want a hit", found, ok)
+	}
+	if got.ID != link.ID || got.PasswordHash != link.PasswordHash {
+		t.Errorf("get on the other replica = %+v, want %+v", got, link)
+	}
+
+	b.setNotFound(ctx, cacheKey(0, "missing"))
+	if _, found, ok := a.get(ctx, cacheKey(0, "missing")); !ok || found {
+		t.Errorf("negative entry = found %t, ok %t, want a cached miss", found, ok)
+	}
+}
+
+func TestLinkCacheInvalidatesOtherReplicas(t *testing.T) {
+	mr := miniredis.RunT(t)
+	a, b := newTestCache(t, mr), newTestCache(t, mr)
+	key := cacheKey(0, "promo")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		b.RunInvalidationListener(ctx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	waitFor(t, "subscription", func() bool {
+		return mr.PubSubNumSub(redisInvalidateChannel)[redisInvalidateChannel] == 1
+	})
+
+	a.set(ctx, key, model.Link{ID: 1, ShortName: "promo"})
+	if _, _, ok := b.get(ctx, key); !ok {
+		t.Fatal("entry did not reach the other replica")
+	}
+
+	a.invalidate(ctx, key)
+
+	if mr.Exists(redisKeyPrefix + key) {
+		t.Error("shared entry is still in Redis")
+	}
+	waitFor(t, "local invalidation", func() bool {
+		_, ok := b.lru.Get(key)
+		return !ok
+	})
+}
+
+func TestLinkCacheFallsBackWhenRedisIsDown(t *testing.T) {
+	mr := miniredis.RunT(t)
+	c := newTestCache(t, mr)
+	c.lru = nil
+	mr.Close()
+
+	ctx := context.Background()
+	c.set(ctx, cacheKey(0, "promo"), model.Link{ID: 1})
+	if _, _, ok := c.get(ctx, cacheKey(0, "promo")); ok {
+		t.Error("get reported a hit with Redis down")
+	}
+}
+
+func TestLinkCacheInvalidateDomainLinks(t *testing.T) {
+	mr := miniredis.RunT(t)
+	c := newTestCache(t, mr)
+	ctx := context.Background()
+
+	c.set(ctx, cacheKey(7, "promo"), model.Link{ID: 1})
+	c.set(ctx, cacheKey(0, "promo"), model.Link{ID: 2})
+	c.InvalidateDomainLinks(ctx, 7, []string{"Promo"})
+
+	if _, _, ok := c.get(ctx, cacheKey(7, "promo")); ok {
+		t.Error("link of the deleted domain is still cached")
+	}
+	if _, _, ok := c.get(ctx, cacheKey(0, "promo")); !ok {
+		t.Error("link with the same name on another domain was dropped")
+	}
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,77 @@
+package link
+
+import (
+	"context"
+	"fmt"
+
+	"markoni23/url-shortener/internal/model"
+	"markoni23/url-shortener/internal/pagination"
+
+	"github.com/lib/pq"
+)
+
+// linkSortColumns maps the sort fields of GetAll to columns.
+var linkSortColumns = map[string]string{
+	"id":           "id",
+	"original_url": "original_url",
+	"short_name":   "short_name",
+	"created_at":   "created_at",
+	"updated_at":   "updated_at",
+	"expires_at":   "expires_at",
+	"max_visits":   "max_visits",
+}
+
+// sortedLinkIDs has the filters of GetLinksCount. sqlc cannot take the
+// ORDER BY column as a parameter, and choosing it with CASE expressions
+// keeps PostgreSQL from using an index for any order, so only this query
+// is built here; the links themselves are read with GetLinksByIDs.
+const sortedLinkIDs = `SELECT id FROM links
+WHERE owner_id = $1
+  AND ($2::boolean IS NULL OR EXISTS (
+    SELECT 1 FROM link_health
+    WHERE link_health.link_id = links.id
+      AND link_health.broken = $2::boolean
+  ))
+  AND ($3::text IS NULL
+    OR original_url ILIKE $3::text
+    OR short_name ILIKE $3::text)
+  AND ($4::bigint[] IS NULL OR id = ANY($4::bigint[]))
+  AND ($5::timestamp IS NULL OR created_at >= $5::timestamp)
+  AND ($6::timestamp IS NULL OR created_at < $6::timestamp)
+%s
+LIMIT $7
+OFFSET $8`
+
+// listLinkIDs returns the ids of one range of the owner's links in the
+// requested order.
+func (s *service) listLinkIDs(ctx context.Context, ownerID int64, filter model.LinkFilter, sort pagination.Sort, limit, offset int64) ([]int64, error) {
+	order, err := sort.OrderBy(linkSortColumns)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.QueryContext(ctx, fmt.Sprintf(sortedLinkIDs, order),
+		toOwnerID(ownerID),
+		toNullBool(filter.Broken),
+		toLikePattern(filter.Query),
+		pq.Array(filter.IDs),
+		toNullTime(filter.CreatedFrom),
+		toNullTime(filter.CreatedTo),
+		limit,
+		offset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
@@ -5,58 +5,142 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
-	"math/rand/v2"
+	"log/slog"
+	"net/url"
+	"strings"
+	"time"
 
+	"markoni23/url-shortener/internal/cache"
 	"markoni23/url-shortener/internal/model"
+	"markoni23/url-shortener/internal/pagination"
+	"markoni23/url-shortener/internal/service/domain"
 	"markoni23/url-shortener/internal/sqlcdb"
+	"markoni23/url-shortener/internal/urlpolicy"
+	"markoni23/url-shortener/internal/utils"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/crypto/bcrypt"
 )
 
 type service struct {
-	basePath string
-	queries  *sqlcdb.Queries
+	basePath      string
+	rootRedirects bool
+	db            *sql.DB
+	queries       *sqlcdb.Queries
+	cache         *LinkCache
+	policy        *urlpolicy.Policy
+	domains       *domain.Registry
+	codes         *ShortCodeGenerators
 }
 
-func NewService(basePath string, queries *sqlcdb.Queries) *service {
+func NewService(basePath string, rootRedirects bool, db *sql.DB, queries *sqlcdb.Queries, cache *LinkCache, policy *urlpolicy.Policy, domains *domain.Registry, codes *ShortCodeGenerators) *service {
 	return &service{
-		basePath: basePath,
-		queries:  queries,
+		basePath:      basePath,
+		rootRedirects: rootRedirects,
+		db:            db,
+		queries:       queries,
+		cache:         cache,
+		policy:        policy,
+		domains:       domains,
+		codes:         codes,
 	}
 }
 
-func (s *service) Count(ctx context.Context) (int64, error) {
-	return s.queries.GetLinksCount(ctx)
+func (s *service) Count(ctx context.Context, ownerID int64, filter model.LinkFilter) (int64, error) {
+	return s.queries.GetLinksCount(ctx, sqlcdb.GetLinksCountParams{
+		OwnerID:     toOwnerID(ownerID),
+		Broken:      toNullBool(filter.Broken),
+		Q:           toLikePattern(filter.Query),
+		Ids:         filter.IDs,
+		CreatedFrom: toNullTime(filter.CreatedFrom),
+		CreatedTo:   toNullTime(filter.CreatedTo),
+	})
 }
 
-func (s *service) GetAll(ctx context.Context, from, to int64) ([]model.Link, error) {
+func (s *service) GetAll(ctx context.Context, ownerID, from, to int64, filter model.LinkFilter, sort pagination.Sort) ([]model.Link, error) {
 	if from < 0 || to <= 0 {
-		return []model.Link{}, errors.New("from and to must be greater than zero")
+		return []model.Link{}, &model.InvalidParamsError{Reason: "from and to must be greater than zero"}
 	}
 
 	if from >= to {
-		return []model.Link{}, errors.New("from must be less than to")
+		return []model.Link{}, &model.InvalidParamsError{Reason: "from must be less than to"}
 	}
 
-	limit := to - from + 1
-	offset := from
-	linksRaw, err := s.queries.GetLinks(ctx, sqlcdb.GetLinksParams{
-		Limit:  int32(limit),
-		Offset: int32(offset),
-	})
-
+	ids, err := s.listLinkIDs(ctx, ownerID, filter, sort, to-from+1, from)
+	if err != nil {
+		return []model.Link{}, err
+	}
+	linksRaw, err := s.queries.GetLinksByIDs(ctx, ids)
 	if err != nil {
 		return []model.Link{}, err
 	}
 
-	res := make([]model.Link, len(linksRaw))
-	for i, raw := range linksRaw {
-		res[i] = s.rawToModel(raw)
+	byID := make(map[int64]sqlcdb.Link, len(linksRaw))
+	for _, raw := range linksRaw {
+		byID[raw.ID] = raw
+	}
+
+	// A link deleted between the two queries is left out.
+	res := make([]model.Link, 0, len(ids))
+	for _, id := range ids {
+		raw, ok := byID[id]
+		if !ok {
+			continue
+		}
+		link, err := s.rawToModel(ctx, raw)
+		if err != nil {
+			return []model.Link{}, err
+		}
+		res = append(res, link)
 	}
 
 	return res, nil
 }
 
-func (s *service) Get(ctx context.Context, id int64) (model.Link, error) {
-	link, err := s.queries.GetLink(ctx, id)
+// GetPage returns up to page.Limit links after the cursor in id order, and
+// the cursor of the next page or nil when this is the last one.
+func (s *service) GetPage(ctx context.Context, ownerID int64, page pagination.Params, filter model.LinkFilter) ([]model.Link, *pagination.Cursor, error) {
+	var afterID int64
+	if page.After != nil {
+		afterID = page.After.ID
+	}
+
+	// One extra row tells whether there is a next page.
+	linksRaw, err := s.queries.GetLinksAfter(ctx, sqlcdb.GetLinksAfterParams{
+		OwnerID:     toOwnerID(ownerID),
+		AfterID:     afterID,
+		Broken:      toNullBool(filter.Broken),
+		Q:           toLikePattern(filter.Query),
+		Ids:         filter.IDs,
+		CreatedFrom: toNullTime(filter.CreatedFrom),
+		CreatedTo:   toNullTime(filter.CreatedTo),
+		Limit:       int32(page.Limit + 1),
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var next *pagination.Cursor
+	if len(linksRaw) > page.Limit {
+		linksRaw = linksRaw[:page.Limit]
+		next = &pagination.Cursor{ID: linksRaw[page.Limit-1].ID}
+	}
+
+	res := make([]model.Link, len(linksRaw))
+	for i, raw := range linksRaw {
+		if res[i], err = s.rawToModel(ctx, raw); err != nil {
+			return nil, nil, err
+		}
+	}
+	return res, next, nil
+}
+
+func (s *service) Get(ctx context.Context, ownerID, id int64) (model.Link, error) {
+	link, err := s.queries.GetLink(ctx, sqlcdb.GetLinkParams{
+		ID:      id,
+		OwnerID: toOwnerID(ownerID),
+	})
 	if err != nil {
 		switch {
 		case errors.Is(err, sql.ErrNoRows):
@@ -65,27 +149,118 @@ func (s *service) Get(ctx context.Context, id int64) (model.Link, error) {
 			return model.Link{}, err
 		}
 	}
-	return s.rawToModel(link), nil
+	return s.rawToModel(ctx, link)
 }
 
-func (s *service) GetLinkByShortName(ctx context.Context, shortName string) (model.Link, error) {
-	link, err := s.queries.GetLinkByShortName(ctx, sql.NullString{String: shortName, Valid: true})
+// GetLinkByShortName resolves a short name in the namespace of the domain
+// served at host. Hosts that are not custom domains use the default
+// namespace of BASE_PATH.
+func (s *service) GetLinkByShortName(ctx context.Context, host, shortName string) (model.Link, error) {
+	domainID, ok, err := s.domains.Lookup(ctx, host)
+	if err != nil {
+		return model.Link{}, err
+	}
+	if !ok {
+		domainID = 0
+	}
+
+	key := cacheKey(domainID, shortName)
+	cached, found, ok := s.cache.get(ctx, key)
+	trace.SpanFromContext(ctx).SetAttributes(attribute.Bool("link.cache_hit", ok))
+	if ok {
+		if !found {
+			return model.Link{}, &model.LinkNotFoundError{}
+		}
+		return cached, nil
+	}
+
+	raw, err := s.queries.GetLinkByShortName(ctx, sqlcdb.GetLinkByShortNameParams{
+		ShortName: shortName,
+		DomainID:  sql.NullInt64{Int64: domainID, Valid: domainID != 0},
+	})
 	if err != nil {
 		switch {
 		case errors.Is(err, sql.ErrNoRows):
+			s.cache.setNotFound(ctx, key)
 			return model.Link{}, &model.LinkNotFoundError{}
 		default:
 			return model.Link{}, err
 		}
 	}
-	return s.rawToModel(link), nil
+
+	link, err := s.rawToModel(ctx, raw)
+	if err != nil {
+		return model.Link{}, err
+	}
+	s.cache.set(ctx, key, link)
+	return link, nil
+}
+
+// Health returns the result of the last destination check of the link.
+func (s *service) Health(ctx context.Context, ownerID, id int64) (model.LinkHealth, error) {
+	if _, err := s.Get(ctx, ownerID, id); err != nil {
+		return model.LinkHealth{}, err
+	}
+
+	raw, err := s.queries.GetLinkHealth(ctx, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.LinkHealth{LinkID: id, Status: model.HealthStatusUnknown}, nil
+		}
+		return model.LinkHealth{}, err
+	}
+
+	latency := int64(raw.LatencyMs)
+	health := model.LinkHealth{
+		LinkID:    id,
+		Status:    model.HealthStatusOK,
+		Error:     raw.Error.String,
+		LatencyMs: &latency,
+		CheckedAt: &raw.CheckedAt,
+	}
+	if raw.Broken {
+		health.Status = model.HealthStatusBroken
+	}
+	if raw.StatusCode.Valid {
+		code := int(raw.StatusCode.Int32)
+		health.StatusCode = &code
+	}
+	return health, nil
 }
 
-func (s *service) Update(ctx context.Context, id int64, originalURL, shortName string) (model.Link, error) {
+func (s *service) CacheStats() cache.Stats {
+	return s.cache.Stats()
+}
+
+func (s *service) Update(ctx context.Context, ownerID, id int64, input model.LinkInput) (model.Link, error) {
+	current, err := s.Get(ctx, ownerID, id)
+	if err != nil {
+		return model.Link{}, err
+	}
+
+	if err := s.policy.Check(ctx, input.OriginalUrl); err != nil {
+		return model.Link{}, err
+	}
+
+	if err := s.checkDomain(ctx, ownerID, input.DomainID); err != nil {
+		return model.Link{}, err
+	}
+
+	passwordHash, err := hashPassword(input.Password)
+	if err != nil {
+		return model.Link{}, err
+	}
+
 	res, err := s.queries.UpdateLink(ctx, sqlcdb.UpdateLinkParams{
-		ID:          id,
-		OriginalUrl: sql.NullString{String: originalURL, Valid: true},
-		ShortName:   sql.NullString{String: shortName, Valid: true},
+		ID:           id,
+		OwnerID:      toOwnerID(ownerID),
+		OriginalUrl:  input.OriginalUrl,
+		ShortName:    input.ShortName,
+		DomainID:     toNullInt64(input.DomainID),
+		ExpiresAt:    toNullTime(input.ExpiresAt),
+		MaxVisits:    toNullInt64(input.MaxVisits),
+		SetPassword:  input.Password != nil,
+		PasswordHash: passwordHash,
 	})
 	if err != nil {
 		switch {
@@ -95,11 +270,27 @@ func (s *service) Update(ctx context.Context, id int64, originalURL, shortName s
 			return model.Link{}, err
 		}
 	}
-	return s.rawToModel(res), nil
+
+	s.cache.invalidate(ctx,
+		linkCacheKey(current.DomainID, current.ShortName),
+		cacheKey(res.DomainID.Int64, res.ShortName),
+	)
+
+	// The last check says nothing about a new destination.
+	if current.OriginalUrl != res.OriginalUrl {
+		if err := s.queries.DeleteLinkHealth(ctx, id); err != nil {
+			slog.ErrorContext(ctx, "failed to reset link health", "link_id", id, "error", err)
+		}
+	}
+
+	return s.rawToModel(ctx, res)
 }
 
-func (s *service) Delete(ctx context.Context, id int64) error {
-	_, err := s.queries.GetLink(ctx, id)
+func (s *service) Delete(ctx context.Context, ownerID, id int64) error {
+	link, err := s.queries.GetLink(ctx, sqlcdb.GetLinkParams{
+		ID:      id,
+		OwnerID: toOwnerID(ownerID),
+	})
 	if err != nil {
 		switch {
 		case errors.Is(err, sql.ErrNoRows):
@@ -108,43 +299,473 @@ func (s *service) Delete(ctx context.Context, id int64) error {
 			return err
 		}
 	}
-	return s.queries.DeleteLink(ctx, id)
+
+	err = s.queries.DeleteLink(ctx, sqlcdb.DeleteLinkParams{
+		ID:      id,
+		OwnerID: toOwnerID(ownerID),
+	})
+	if err != nil {
+		return err
+	}
+
+	s.cache.invalidate(ctx, cacheKey(link.DomainID.Int64, link.ShortName))
+	return nil
 }
 
-func (s *service) Create(ctx context.Context, originalURL, shortName string) (model.Link, error) {
-	if shortName == "" {
-		shortName = GenerateShortName()
+func (s *service) Create(ctx context.Context, ownerID int64, input model.LinkInput) (model.Link, error) {
+	if err := s.policy.Check(ctx, input.OriginalUrl); err != nil {
+		return model.Link{}, err
 	}
 
-	res, err := s.queries.CreateLink(ctx, sqlcdb.CreateLinkParams{
-		OriginalUrl: sql.NullString{String: originalURL, Valid: true},
-		ShortName:   sql.NullString{String: shortName, Valid: true},
-	})
+	if err := s.checkDomain(ctx, ownerID, input.DomainID); err != nil {
+		return model.Link{}, err
+	}
 
+	passwordHash, err := hashPassword(input.Password)
 	if err != nil {
 		return model.Link{}, err
 	}
 
-	return s.rawToModel(res), nil
+	res, err := s.insertLink(ctx, s.queries, nil, ownerID, input, passwordHash)
+	if err != nil {
+		return model.Link{}, err
+	}
+
+	s.cache.invalidate(ctx, cacheKey(res.DomainID.Int64, res.ShortName))
+
+	return s.rawToModel(ctx, res)
 }
 
-const ShortNameLength = 8
+// ValidateBulk runs the checks CreateBulk makes before touching the links
+// table: the URL policy, the domain and duplicate short names within the
+// batch. Failed items get their Errors set; err is only returned when a
+// check itself fails.
+func (s *service) ValidateBulk(ctx context.Context, ownerID int64, inputs []model.LinkInput) ([]model.BulkItemResult, error) {
+	results := make([]model.BulkItemResult, len(inputs))
+	seen := make(map[string]struct{}, len(inputs))
+	domains := make(map[int64]bool)
+
+	for i, input := range inputs {
+		results[i].Index = i
+
+		if err := s.policy.Check(ctx, input.OriginalUrl); err != nil {
+			var disallowed *model.DisallowedURLError
+			if !errors.As(err, &disallowed) {
+				return nil, err
+			}
+			results[i].Errors = map[string]string{"original_url": disallowed.Reason}
+			continue
+		}
+
+		if input.DomainID != nil {
+			known, ok := domains[*input.DomainID]
+			if !ok {
+				err := s.checkDomain(ctx, ownerID, input.DomainID)
+				if err != nil && !errors.Is(err, &model.DomainNotFoundError{}) {
+					return nil, err
+				}
+				known = err == nil
+				domains[*input.DomainID] = known
+			}
+			if !known {
+				results[i].Errors = map[string]string{"domain_id": "unknown domain"}
+				continue
+			}
+		}
 
-func GenerateShortName() string {
-	alphabet := "qwertyuiopasdfghjklzxcvbnmQWERTYUIOPASDFGHJKLZXCVBNM"
-	res := make([]byte, ShortNameLength)
-	for i := range ShortNameLength {
-		res[i] = alphabet[rand.IntN(len(alphabet))]
+		if input.ShortName != "" {
+			key := linkCacheKey(input.DomainID, input.ShortName)
+			if _, ok := seen[key]; ok {
+				results[i].Errors = map[string]string{"short_name": "short name already in use"}
+				continue
+			}
+			seen[key] = struct{}{}
+		}
 	}
-	return string(res)
+
+	return results, nil
 }
 
-func (s *service) rawToModel(raw sqlcdb.Link) model.Link {
-	shortUrl := fmt.Sprintf("%s/r/%s", s.basePath, raw.ShortName.String)
-	return model.Link{
+// CreateBulk inserts all links in a single transaction. The items are
+// validated and their passwords hashed before the transaction starts, so
+// it only holds the inserts. Each insert runs in its own savepoint so that
+// every duplicate short name gets reported, but if any item fails nothing
+// is committed and BulkRejectedError is returned.
+func (s *service) CreateBulk(ctx context.Context, ownerID int64, inputs []model.LinkInput) ([]model.BulkItemResult, error) {
+	results, err := s.ValidateBulk(ctx, ownerID, inputs)
+	if err != nil {
+		return nil, err
+	}
+	for _, result := range results {
+		if result.Errors != nil {
+			return results, &model.BulkRejectedError{}
+		}
+	}
+
+	passwordHashes := make([]sql.NullString, len(inputs))
+	for i, input := range inputs {
+		passwordHashes[i], err = hashPassword(input.Password)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	qtx := s.queries.WithTx(tx)
+	failed := false
+
+	for i, input := range inputs {
+		res, err := s.insertLink(ctx, qtx, tx, ownerID, input, passwordHashes[i])
+		if err != nil {
+			if !utils.IsDuplicateKeyError(err) {
+				return nil, err
+			}
+			results[i].Errors = utils.FormatDuplicateKeyError(err, "short_name")
+			failed = true
+			continue
+		}
+
+		link, err := s.rawToModel(ctx, res)
+		if err != nil {
+			return nil, err
+		}
+		results[i].Link = &link
+	}
+
+	if failed {
+		for i := range results {
+			results[i].Link = nil
+		}
+		return results, &model.BulkRejectedError{}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, len(results))
+	for i, result := range results {
+		keys[i] = linkCacheKey(result.Link.DomainID, result.Link.ShortName)
+	}
+	s.cache.invalidate(ctx, keys...)
+
+	return results, nil
+}
+
+// DeleteBulk deletes the caller's links with the given ids and returns the
+// ids that were actually deleted.
+func (s *service) DeleteBulk(ctx context.Context, ownerID int64, ids []int64) ([]int64, error) {
+	rows, err := s.queries.DeleteLinks(ctx, sqlcdb.DeleteLinksParams{
+		OwnerID: toOwnerID(ownerID),
+		Ids:     ids,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	deleted := make([]int64, len(rows))
+	keys := make([]string, len(rows))
+	for i, row := range rows {
+		deleted[i] = row.ID
+		keys[i] = cacheKey(row.DomainID.Int64, row.ShortName)
+	}
+	s.cache.invalidate(ctx, keys...)
+	return deleted, nil
+}
+
+const exportBatchSize = 500
+
+// Export calls fn for every link of the owner in id order. Links are read
+// in batches so the whole table is never loaded into memory at once.
+func (s *service) Export(ctx context.Context, ownerID int64, fn func(model.LinkExport) error) error {
+	var lastID int64
+	for {
+		rows, err := s.queries.GetLinksForExport(ctx, sqlcdb.GetLinksForExportParams{
+			OwnerID: toOwnerID(ownerID),
+			ID:      lastID,
+			Limit:   exportBatchSize,
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, row := range rows {
+			baseURL, err := s.baseURL(ctx, row.DomainID)
+			if err != nil {
+				return err
+			}
+
+			export := model.LinkExport{
+				ID:          row.ID,
+				OriginalUrl: row.OriginalUrl,
+				ShortName:   row.ShortName,
+				ShortUrl:    s.shortUrl(baseURL, row.ShortName),
+				Visits:      row.Visits,
+				CreatedAt:   row.CreatedAt,
+			}
+			if row.ExpiresAt.Valid {
+				export.ExpiresAt = &row.ExpiresAt.Time
+			}
+			if row.MaxVisits.Valid {
+				export.MaxVisits = &row.MaxVisits.Int64
+			}
+
+			if err := fn(export); err != nil {
+				return err
+			}
+			lastID = row.ID
+		}
+
+		if len(rows) < exportBatchSize {
+			return nil
+		}
+	}
+}
+
+// ExistingShortNames returns which of the given short names are already
+// taken in the namespace of the domain, or of BASE_PATH when domainID is nil.
+// Short names are compared case-insensitively; the result is keyed by the
+// names as given.
+func (s *service) ExistingShortNames(ctx context.Context, domainID *int64, shortNames []string) (map[string]bool, error) {
+	lowered := make([]string, len(shortNames))
+	for i, name := range shortNames {
+		lowered[i] = strings.ToLower(name)
+	}
+
+	rows, err := s.queries.GetExistingShortNames(ctx, sqlcdb.GetExistingShortNamesParams{
+		ShortNames: lowered,
+		DomainID:   toNullInt64(domainID),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	taken := make(map[string]bool, len(rows))
+	for _, row := range rows {
+		taken[strings.ToLower(row)] = true
+	}
+
+	existing := make(map[string]bool, len(rows))
+	for _, name := range shortNames {
+		if taken[strings.ToLower(name)] {
+			existing[name] = true
+		}
+	}
+	return existing, nil
+}
+
+// VerifyPassword reports whether password unlocks the link. Links without a
+// password are always unlocked.
+func (s *service) VerifyPassword(link model.Link, password string) bool {
+	if link.PasswordHash == "" {
+		return true
+	}
+	return bcrypt.CompareHashAndPassword([]byte(link.PasswordHash), []byte(password)) == nil
+}
+
+// RunExpirationSweeper periodically marks links whose expiration time has
+// passed or whose visit limit is exhausted. It blocks until ctx is cancelled.
+func (s *service) RunExpirationSweeper(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n, err := s.queries.MarkExpiredLinks(ctx)
+			if err != nil {
+				slog.ErrorContext(ctx, "expiration sweeper failed", "error", err)
+				continue
+			}
+			if n > 0 {
+				slog.InfoContext(ctx, "expiration sweeper marked links as expired", "count", n)
+			}
+		}
+	}
+}
+
+// insertLink creates the link with q. When the input has no short name one
+// is generated, and regenerated if it is already taken. Inside a
+// transaction tx must be set: every attempt then runs in a savepoint, since
+// a failed statement would otherwise abort the whole transaction.
+func (s *service) insertLink(ctx context.Context, q *sqlcdb.Queries, tx *sql.Tx, ownerID int64, input model.LinkInput, passwordHash sql.NullString) (sqlcdb.Link, error) {
+	generate := input.ShortName == ""
+	generator, err := s.codes.Get(input.Generator)
+	if err != nil {
+		return sqlcdb.Link{}, err
+	}
+
+	for attempt := 1; ; attempt++ {
+		if generate {
+			if input.ShortName, err = generator.Generate(ctx); err != nil {
+				return sqlcdb.Link{}, err
+			}
+		}
+
+		if tx != nil {
+			if _, err := tx.ExecContext(ctx, "SAVEPOINT create_link"); err != nil {
+				return sqlcdb.Link{}, err
+			}
+		}
+
+		res, err := q.CreateLink(ctx, sqlcdb.CreateLinkParams{
+			OriginalUrl:  input.OriginalUrl,
+			ShortName:    input.ShortName,
+			ExpiresAt:    toNullTime(input.ExpiresAt),
+			MaxVisits:    toNullInt64(input.MaxVisits),
+			PasswordHash: passwordHash,
+			OwnerID:      toOwnerID(ownerID),
+			DomainID:     toNullInt64(input.DomainID),
+		})
+		if err == nil {
+			if tx != nil {
+				if _, err := tx.ExecContext(ctx, "RELEASE SAVEPOINT create_link"); err != nil {
+					return sqlcdb.Link{}, err
+				}
+			}
+			return res, nil
+		}
+
+		if tx != nil {
+			if _, rollbackErr := tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT create_link"); rollbackErr != nil {
+				return sqlcdb.Link{}, rollbackErr
+			}
+		}
+
+		if !generate || !utils.IsDuplicateKeyError(err) || attempt == maxGenerateAttempts {
+			return sqlcdb.Link{}, err
+		}
+	}
+}
+
+// checkDomain makes sure the owner may create links on the domain. A nil
+// domainID stands for BASE_PATH, which everybody may use.
+func (s *service) checkDomain(ctx context.Context, ownerID int64, domainID *int64) error {
+	if domainID == nil {
+		return nil
+	}
+
+	_, err := s.queries.GetDomain(ctx, sqlcdb.GetDomainParams{
+		ID:      *domainID,
+		OwnerID: ownerID,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return &model.DomainNotFoundError{}
+	}
+	return err
+}
+
+// baseURL returns the URL short links of the domain are served from. Custom
+// domains use the scheme of BASE_PATH.
+func (s *service) baseURL(ctx context.Context, domainID sql.NullInt64) (string, error) {
+	if !domainID.Valid {
+		return s.basePath, nil
+	}
+
+	host, err := s.domains.Host(ctx, domainID.Int64)
+	if err != nil {
+		return "", err
+	}
+
+	scheme := "https"
+	if u, err := url.Parse(s.basePath); err == nil && u.Scheme != "" {
+		scheme = u.Scheme
+	}
+	return scheme + "://" + host, nil
+}
+
+// shortUrl builds the public URL of a short name. With root redirects the
+// /r/ segment is left out, except for names that are shadowed by reserved
+// paths and therefore only work under /r/.
+func (s *service) shortUrl(baseURL, shortName string) string {
+	if s.rootRedirects && !model.IsReservedShortName(shortName) {
+		return fmt.Sprintf("%s/%s", baseURL, shortName)
+	}
+	return fmt.Sprintf("%s/r/%s", baseURL, shortName)
+}
+
+func (s *service) rawToModel(ctx context.Context, raw sqlcdb.Link) (model.Link, error) {
+	baseURL, err := s.baseURL(ctx, raw.DomainID)
+	if err != nil {
+		return model.Link{}, err
+	}
+
+	link := model.Link{
 		ID:          raw.ID,
-		OriginalUrl: raw.OriginalUrl.String,
-		ShortName:   raw.ShortName.String,
-		ShortUrl:    shortUrl,
+		OriginalUrl: raw.OriginalUrl,
+		ShortName:   raw.ShortName,
+		ShortUrl:    s.shortUrl(baseURL, raw.ShortName),
+		Expired:     raw.Expired,
+		HasPassword: raw.PasswordHash.Valid,
+		CreatedAt:   raw.CreatedAt,
+		UpdatedAt:   raw.UpdatedAt,
+
+		PasswordHash: raw.PasswordHash.String,
+	}
+	if raw.DomainID.Valid {
+		link.DomainID = &raw.DomainID.Int64
+	}
+	if raw.ExpiresAt.Valid {
+		link.ExpiresAt = &raw.ExpiresAt.Time
+	}
+	if raw.MaxVisits.Valid {
+		link.MaxVisits = &raw.MaxVisits.Int64
+	}
+	return link, nil
+}
+
+func hashPassword(password *string) (sql.NullString, error) {
+	if password == nil || *password == "" {
+		return sql.NullString{}, nil
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(*password), bcrypt.DefaultCost)
+	if err != nil {
+		return sql.NullString{}, err
+	}
+	return sql.NullString{String: string(hash), Valid: true}, nil
+}
+
+func toOwnerID(ownerID int64) sql.NullInt64 {
+	return sql.NullInt64{Int64: ownerID, Valid: true}
+}
+
+func toNullTime(t *time.Time) sql.NullTime {
+	if t == nil {
+		return sql.NullTime{}
+	}
+	return sql.NullTime{Time: t.UTC(), Valid: true}
+}
+
+// toLikePattern turns a search term into an ILIKE pattern matching it as a
+// substring. Wildcards in the term are matched literally.
+func toLikePattern(q string) sql.NullString {
+	if q == "" {
+		return sql.NullString{}
+	}
+	escaped := strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(q)
+	return sql.NullString{String: "%" + escaped + "%", Valid: true}
+}
+
+func toNullBool(v *bool) sql.NullBool {
+	if v == nil {
+		return sql.NullBool{}
+	}
+	return sql.NullBool{Bool: *v, Valid: true}
+}
+
+func toNullInt64(v *int64) sql.NullInt64 {
+	if v == nil {
+		return sql.NullInt64{}
 	}
+	return sql.NullInt64{Int64: *v, Valid: true}
 }
@@ -0,0 +1,291 @@
+package link
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"math/bits"
+	mathrand "math/rand/v2"
+	"strings"
+	"unicode"
+
+	"markoni23/url-shortener/internal/config"
+)
+
+const (
+	GeneratorRandom   = "random"
+	GeneratorSequence = "sequence"
+	GeneratorWords    = "words"
+)
+
+// maxShortNameLength is the longest short name the API accepts.
+const maxShortNameLength = 32
+
+// maxGenerateAttempts bounds how often a generated short name is retried
+// after it turned out to be taken.
+const maxGenerateAttempts = 5
+
+// ShortCodeGenerator produces short names for links created without one.
+// Generated names may collide with existing ones; the service retries on
+// unique violations.
+type ShortCodeGenerator interface {
+	Generate(ctx context.Context) (string, error)
+}
+
+// ShortCodeGenerators holds the available strategies and the one used when
+// a request does not pick any.
+type ShortCodeGenerators struct {
+	byName      map[string]ShortCodeGenerator
+	defaultName string
+}
+
+// NewShortCodeGenerators builds every strategy from cfg. next returns the
+// next value of the sequence used by the sequence strategy. Every strategy
+// must produce names the API would accept, so the alphabet may only hold
+// characters that need no escaping in a URL path, and no letter in both
+// cases since short names are unique regardless of case.
+func NewShortCodeGenerators(cfg config.ShortCodeConfig, next func(ctx context.Context) (int64, error)) (*ShortCodeGenerators, error) {
+	alphabet := []rune(cfg.Alphabet)
+	if len(alphabet) < 2 || hasDuplicates(alphabet) {
+		return nil, errors.New("short code alphabet must contain at least 2 distinct characters, ignoring case")
+	}
+	for _, r := range alphabet {
+		if !isUnreserved(r) {
+			return nil, fmt.Errorf("short code alphabet contains %q; only letters, digits and -._~ are allowed", r)
+		}
+	}
+	if cfg.Length < 4 {
+		return nil, errors.New("short code length must be at least 4")
+	}
+	if cfg.Words < 1 {
+		return nil, errors.New("short code words must be at least 1")
+	}
+
+	sequence := NewSequenceGenerator(next, alphabet, cfg.Length, cfg.Salt)
+	if n := len(sequence.encode(math.MaxInt64, cfg.Length)); n > maxShortNameLength {
+		return nil, fmt.Errorf("short code length and alphabet produce names of up to %d characters, more than %d", n, maxShortNameLength)
+	}
+	words := NewWordsGenerator(cfg.Words)
+	if n := words.maxLength(); n > maxShortNameLength {
+		return nil, fmt.Errorf("short code words produce names of up to %d characters, more than %d", n, maxShortNameLength)
+	}
+
+	g := &ShortCodeGenerators{
+		byName: map[string]ShortCodeGenerator{
+			GeneratorRandom:   NewRandomGenerator(alphabet, cfg.Length),
+			GeneratorSequence: sequence,
+			GeneratorWords:    words,
+		},
+		defaultName: cfg.Generator,
+	}
+	if _, ok := g.byName[cfg.Generator]; !ok {
+		return nil, fmt.Errorf("unknown short code generator %q", cfg.Generator)
+	}
+	return g, nil
+}
+
+// Get returns the generator with the given name, or the default one when
+// name is empty.
+func (g *ShortCodeGenerators) Get(name string) (ShortCodeGenerator, error) {
+	if name == "" {
+		name = g.defaultName
+	}
+	generator, ok := g.byName[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown short code generator %q", name)
+	}
+	return generator, nil
+}
+
+type randomGenerator struct {
+	alphabet []rune
+	length   int
+}
+
+// NewRandomGenerator returns a generator of length characters drawn from
+// alphabet with crypto/rand.
+func NewRandomGenerator(alphabet []rune, length int) *randomGenerator {
+	return &randomGenerator{
+		alphabet: alphabet,
+		length:   length,
+	}
+}
+
+func (g *randomGenerator) Generate(_ context.Context) (string, error) {
+	size := big.NewInt(int64(len(g.alphabet)))
+	res := make([]rune, g.length)
+	for i := range res {
+		n, err := rand.Int(rand.Reader, size)
+		if err != nil {
+			return "", err
+		}
+		res[i] = g.alphabet[n.Int64()]
+	}
+	return string(res), nil
+}
+
+// sequenceMultiplier is a prime larger than any sensible alphabet, so it is
+// coprime with every power of the alphabet size.
+const sequenceMultiplier = 2147483647
+
+type sequenceGenerator struct {
+	next      func(ctx context.Context) (int64, error)
+	alphabet  []rune
+	minLength int
+	space     uint64
+}
+
+// NewSequenceGenerator encodes values of a database sequence in the
+// alphabet, shuffled with salt, in the spirit of Hashids. Values below
+// len(alphabet)^minLength are scrambled by a modular multiplication so that
+// consecutive links do not get consecutive names; all such names have
+// exactly minLength characters. Larger values are encoded as they are and
+// are always longer, so names never repeat.
+func NewSequenceGenerator(next func(ctx context.Context) (int64, error), alphabet []rune, minLength int, salt string) *sequenceGenerator {
+	shuffled := append([]rune(nil), alphabet...)
+	seed := sha256.Sum256([]byte(salt))
+	rng := mathrand.New(mathrand.NewPCG(
+		binary.BigEndian.Uint64(seed[:8]),
+		binary.BigEndian.Uint64(seed[8:16]),
+	))
+	rng.Shuffle(len(shuffled), func(i, j int) {
+		shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
+	})
+
+	space := uint64(1)
+	for range minLength {
+		hi, lo := bits.Mul64(space, uint64(len(alphabet)))
+		if hi != 0 {
+			space = 0
+			break
+		}
+		space = lo
+	}
+
+	return &sequenceGenerator{
+		next:      next,
+		alphabet:  shuffled,
+		minLength: minLength,
+		space:     space,
+	}
+}
+
+func (g *sequenceGenerator) Generate(ctx context.Context) (string, error) {
+	value, err := g.next(ctx)
+	if err != nil {
+		return "", err
+	}
+	if value < 0 {
+		return "", errors.New("negative sequence value")
+	}
+
+	n := uint64(value)
+	if g.space == 0 || n < g.space {
+		hi, lo := bits.Mul64(n, sequenceMultiplier)
+		n = lo
+		if g.space != 0 {
+			n = bits.Rem64(hi, lo, g.space)
+		}
+		return g.encode(n, g.minLength), nil
+	}
+	return g.encode(n, g.minLength+1), nil
+}
+
+func (g *sequenceGenerator) encode(n uint64, minLength int) string {
+	base := uint64(len(g.alphabet))
+	var res []rune
+	for n > 0 || len(res) < minLength {
+		res = append(res, g.alphabet[n%base])
+		n /= base
+	}
+	return string(res)
+}
+
+type wordsGenerator struct {
+	count int
+}
+
+// NewWordsGenerator returns a generator of count dictionary words joined
+// by dashes, such as "amber-falcon-river".
+func NewWordsGenerator(count int) *wordsGenerator {
+	return &wordsGenerator{count: count}
+}
+
+func (g *wordsGenerator) Generate(_ context.Context) (string, error) {
+	size := big.NewInt(int64(len(shortCodeWords)))
+	words := make([]string, g.count)
+	for i := range words {
+		n, err := rand.Int(rand.Reader, size)
+		if err != nil {
+			return "", err
+		}
+		words[i] = shortCodeWords[n.Int64()]
+	}
+	return strings.Join(words, "-"), nil
+}
+
+// maxLength returns the length of the longest name the generator can
+// produce.
+func (g *wordsGenerator) maxLength() int {
+	longest := 0
+	for _, word := range shortCodeWords {
+		longest = max(longest, len(word))
+	}
+	return g.count*longest + g.count - 1
+}
+
+// hasDuplicates reports whether a character occurs twice in alphabet,
+// ignoring case.
+func hasDuplicates(alphabet []rune) bool {
+	seen := make(map[rune]struct{}, len(alphabet))
+	for _, r := range alphabet {
+		r = unicode.ToLower(r)
+		if _, ok := seen[r]; ok {
+			return true
+		}
+		seen[r] = struct{}{}
+	}
+	return false
+}
+
+// isUnreserved reports whether r is an unreserved URL character as defined
+// by RFC 3986.
+func isUnreserved(r rune) bool {
+	switch {
+	case 'a' <= r && r <= 'z', 'A' <= r && r <= 'Z', '0' <= r && r <= '9':
+		return true
+	default:
+		return strings.ContainsRune("-._~", r)
+	}
+}
+
+// shortCodeWords is a list of short, unambiguous English words.
+var shortCodeWords = []string{
+	"acorn", "amber", "anchor", "apple", "arrow", "aspen", "atlas", "autumn",
+	"badge", "bamboo", "banjo", "basil", "beacon", "berry", "birch", "bison",
+	"blaze", "bloom", "breeze", "brook", "cabin", "cactus", "camel", "candle",
+	"canyon", "cedar", "chalk", "cherry", "cider", "cloud", "clover", "cobalt",
+	"comet", "copper", "coral", "cosmic", "cotton", "crane", "cricket", "crystal",
+	"daisy", "dawn", "delta", "denim", "desert", "dolphin", "dragon", "drift",
+	"eagle", "echo", "ember", "falcon", "fern", "fiddle", "finch", "flint",
+	"forest", "fossil", "fox", "frost", "galaxy", "garden", "garnet", "gecko",
+	"ginger", "glacier", "golden", "granite", "gravel", "harbor", "hazel", "heron",
+	"hollow", "honey", "horizon", "indigo", "iris", "island", "ivory", "jade",
+	"jasmine", "jungle", "juniper", "kayak", "kettle", "kiwi", "lagoon", "lantern",
+	"lark", "lava", "lemon", "lilac", "linen", "lotus", "lunar", "maple",
+	"marble", "meadow", "melon", "meteor", "mint", "mist", "moss", "nectar",
+	"noble", "nova", "oak", "oasis", "ocean", "olive", "onyx", "opal",
+	"orbit", "orchid", "otter", "owl", "panda", "paper", "pebble", "pepper",
+	"pine", "planet", "plum", "polar", "poppy", "prairie", "quartz", "quill",
+	"rain", "raven", "reef", "ridge", "river", "robin", "rocket", "rose",
+	"ruby", "saffron", "sage", "salmon", "sand", "sapphire", "shadow", "shell",
+	"sierra", "silver", "sky", "slate", "snow", "sparrow", "spruce", "star",
+	"stone", "storm", "summit", "sun", "swan", "thistle", "thunder", "tiger",
+	"timber", "topaz", "tulip", "tundra", "valley", "velvet", "violet", "walnut",
+	"willow", "wind", "winter", "wolf", "zephyr", "zinc", "zebra", "yarrow",
+}
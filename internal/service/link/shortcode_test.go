@@ -0,0 +1,69 @@
+package link
+
+import (
+	"context"
+	"testing"
+
+	"markoni23/url-shortener/internal/config"
+)
+
+func TestNewShortCodeGenerators(t *testing.T) {
+	valid := config.ShortCodeConfig{
+		Generator: GeneratorRandom,
+		Alphabet:  "abcdefghijklmnopqrstuvwxyz0123456789",
+		Length:    8,
+		Words:     3,
+	}
+	next := func(context.Context) (int64, error) { return 1, nil }
+
+	tests := []struct {
+		name    string
+		modify  func(cfg *config.ShortCodeConfig)
+		wantErr bool
+	}{
+		{name: "valid", modify: func(*config.ShortCodeConfig) {}},
+		{name: "mixed case", modify: func(cfg *config.ShortCodeConfig) { cfg.Alphabet = "abcABC" }, wantErr: true},
+		{name: "slash", modify: func(cfg *config.ShortCodeConfig) { cfg.Alphabet = "abc/" }, wantErr: true},
+		{name: "question mark", modify: func(cfg *config.ShortCodeConfig) { cfg.Alphabet = "abc?" }, wantErr: true},
+		{name: "unreserved punctuation", modify: func(cfg *config.ShortCodeConfig) { cfg.Alphabet = "abc-._~" }},
+		{name: "too short", modify: func(cfg *config.ShortCodeConfig) { cfg.Length = 3 }, wantErr: true},
+		{name: "too long", modify: func(cfg *config.ShortCodeConfig) { cfg.Length = 33 }, wantErr: true},
+		{name: "sequence outgrows the limit", modify: func(cfg *config.ShortCodeConfig) { cfg.Alphabet = "ab" }, wantErr: true},
+		{name: "too many words", modify: func(cfg *config.ShortCodeConfig) { cfg.Words = 4 }, wantErr: true},
+		{name: "no words", modify: func(cfg *config.ShortCodeConfig) { cfg.Words = 0 }, wantErr: true},
+		{name: "unknown generator", modify: func(cfg *config.ShortCodeConfig) { cfg.Generator = "uuid" }, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := valid
+			tt.modify(&cfg)
+
+			_, err := NewShortCodeGenerators(cfg, next)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewShortCodeGenerators(%+v) error = %v, want error %t", cfg, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSequenceGeneratorNamesDoNotRepeat(t *testing.T) {
+	var value int64
+	next := func(context.Context) (int64, error) {
+		value++
+		return value, nil
+	}
+	g := NewSequenceGenerator(next, []rune("abc0123"), 4, "salt")
+
+	// 7^4 values fit the minimum length; the rest get longer names.
+	seen := make(map[string]int64)
+	for range 7*7*7*7 + 100 {
+		name, err := g.Generate(context.Background())
+		if err != nil {
+			t.Fatalf("Generate: %v", err)
+		}
+		if previous, ok := seen[name]; ok {
+			t.Fatalf("values %d and %d both produced %q", previous, value, name)
+		}
+		seen[name] = value
+	}
+}
@@ -0,0 +1,226 @@
+package linkhealth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"sync"
+	"syscall"
+	"time"
+
+	"markoni23/url-shortener/internal/config"
+	"markoni23/url-shortener/internal/sqlcdb"
+	"markoni23/url-shortener/internal/urlpolicy"
+)
+
+const (
+	userAgent = "url-shortener-health-checker/1.0"
+	// maxBodyRead limits how much of a GET response is drained so the
+	// connection can be reused.
+	maxBodyRead    = 64 << 10
+	maxErrorLength = 255
+)
+
+var errInternalAddress = errors.New("destination resolves to a private network address")
+
+// Checker periodically requests the destination of every link and records
+// whether it is still reachable. Links are checked again once RecheckAfter
+// has passed since their last check.
+//
+// Requests to the same host are made one at a time with HostDelay between
+// them, and at most Concurrency hosts are checked in parallel.
+type Checker struct {
+	queries *sqlcdb.Queries
+	client  *http.Client
+	cfg     config.HealthCheckConfig
+	now     func() time.Time
+	// isInternal reports addresses the checker must not connect to.
+	isInternal func(netip.Addr) bool
+}
+
+func NewChecker(queries *sqlcdb.Queries, cfg config.HealthCheckConfig) *Checker {
+	c := &Checker{
+		queries:    queries,
+		cfg:        cfg,
+		now:        time.Now,
+		isInternal: urlpolicy.IsInternal,
+	}
+
+	dialer := &net.Dialer{
+		Timeout: cfg.Timeout,
+		// Destinations are user supplied, so connections to internal
+		// addresses are refused even if DNS changed after the link was
+		// created or a redirect points there.
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			addr, err := netip.ParseAddr(host)
+			if err != nil {
+				return err
+			}
+			if c.isInternal(addr) {
+				return errInternalAddress
+			}
+			return nil
+		},
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	c.client = &http.Client{
+		Transport: transport,
+		Timeout:   cfg.Timeout,
+	}
+	return c
+}
+
+// Run checks due links every Interval. It blocks until ctx is cancelled.
+func (c *Checker) Run(ctx context.Context) {
+	ticker := time.NewTicker(c.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := c.checkDue(ctx); err != nil && ctx.Err() == nil {
+				slog.ErrorContext(ctx, "health checker failed", "error", err)
+			}
+		}
+	}
+}
+
+type dueLink struct {
+	id  int64
+	url string
+}
+
+func (c *Checker) checkDue(ctx context.Context) error {
+	rows, err := c.queries.GetLinksDueForHealthCheck(ctx, sqlcdb.GetLinksDueForHealthCheckParams{
+		CheckedBefore: c.now().UTC().Add(-c.cfg.RecheckAfter),
+		Limit:         int32(c.cfg.BatchSize),
+	})
+	if err != nil {
+		return err
+	}
+
+	byHost := make(map[string][]dueLink)
+	for _, row := range rows {
+		var host string
+		if u, err := url.Parse(row.OriginalUrl); err == nil {
+			host = u.Hostname()
+		}
+		byHost[host] = append(byHost[host], dueLink{id: row.ID, url: row.OriginalUrl})
+	}
+
+	sem := make(chan struct{}, c.cfg.Concurrency)
+	var wg sync.WaitGroup
+	for _, links := range byHost {
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+			return ctx.Err()
+		case sem <- struct{}{}:
+		}
+
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			c.checkHost(ctx, links)
+		}()
+	}
+	wg.Wait()
+
+	return nil
+}
+
+func (c *Checker) checkHost(ctx context.Context, links []dueLink) {
+	for i, link := range links {
+		if i > 0 {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(c.cfg.HostDelay):
+			}
+		}
+
+		params := c.check(ctx, link.url)
+		if ctx.Err() != nil {
+			return
+		}
+		params.LinkID = link.id
+
+		if err := c.queries.UpsertLinkHealth(ctx, params); err != nil {
+			slog.ErrorContext(ctx, "health checker failed to save result", "link_id", link.id, "error", err)
+		}
+	}
+}
+
+// check requests rawURL with HEAD and falls back to GET when HEAD fails,
+// since some servers do not implement HEAD correctly. A destination is
+// broken when it cannot be reached or answers with an error status other
+// than 429.
+func (c *Checker) check(ctx context.Context, rawURL string) sqlcdb.UpsertLinkHealthParams {
+	start := c.now()
+
+	status, err := c.request(ctx, http.MethodHead, rawURL)
+	if err != nil || status >= http.StatusBadRequest {
+		status, err = c.request(ctx, http.MethodGet, rawURL)
+	}
+
+	res := sqlcdb.UpsertLinkHealthParams{
+		LatencyMs: int32(c.now().Sub(start).Milliseconds()),
+		CheckedAt: c.now().UTC(),
+	}
+	if err != nil {
+		msg := err.Error()
+		if len(msg) > maxErrorLength {
+			msg = msg[:maxErrorLength]
+		}
+		res.Error = sql.NullString{String: msg, Valid: true}
+		res.Broken = true
+		return res
+	}
+
+	res.StatusCode = sql.NullInt32{Int32: int32(status), Valid: true}
+	res.Broken = status >= http.StatusBadRequest && status != http.StatusTooManyRequests
+	return res
+}
+
+func (c *Checker) request(ctx context.Context, method, rawURL string) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, method, rawURL, nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("User-Agent", userAgent)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if method == http.MethodGet {
+		// The status is all we need; a failure while draining the body
+		// does not make the destination broken.
+		_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxBodyRead))
+	}
+
+	return resp.StatusCode, nil
+}
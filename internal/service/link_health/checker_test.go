@@ -0,0 +1,118 @@
+package linkhealth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+
+	"markoni23/url-shortener/internal/config"
+)
+
+func newTestChecker(t *testing.T) *Checker {
+	t.Helper()
+
+	c := NewChecker(nil, config.HealthCheckConfig{Timeout: 5 * time.Second})
+	// httptest servers listen on loopback, which the checker refuses.
+	c.isInternal = func(netip.Addr) bool { return false }
+	return c
+}
+
+func TestCheck(t *testing.T) {
+	var methods []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		methods = append(methods, r.Method)
+		if got := r.Header.Get("User-Agent"); got != userAgent {
+			t.Errorf("User-Agent = %q, want %q", got, userAgent)
+		}
+
+		switch r.URL.Path {
+		case "/ok":
+		case "/no-head":
+			if r.Method == http.MethodHead {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			_, _ = w.Write([]byte("body"))
+		case "/redirect":
+			http.Redirect(w, r, "/ok", http.StatusFound)
+		case "/limited":
+			w.WriteHeader(http.StatusTooManyRequests)
+		case "/broken":
+			w.WriteHeader(http.StatusServiceUnavailable)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	c := newTestChecker(t)
+	tests := []struct {
+		name        string
+		path        string
+		wantStatus  int32
+		wantBroken  bool
+		wantMethods []string
+	}{
+		{name: "ok", path: "/ok", wantStatus: 200, wantMethods: []string{"HEAD"}},
+		{name: "falls back to get", path: "/no-head", wantStatus: 200, wantMethods: []string{"HEAD", "GET"}},
+		{name: "follows redirects", path: "/redirect", wantStatus: 200, wantMethods: []string{"HEAD", "HEAD"}},
+		{name: "rate limited is not broken", path: "/limited", wantStatus: 429, wantMethods: []string{"HEAD", "GET"}},
+		{name: "server error", path: "/broken", wantStatus: 503, wantBroken: true, wantMethods: []string{"HEAD", "GET"}},
+		{name: "not found", path: "/missing", wantStatus: 404, wantBroken: true, wantMethods: []string{"HEAD", "GET"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			methods = nil
+			got := c.check(context.Background(), srv.URL+tt.path)
+
+			if got.Error.Valid {
+				t.Fatalf("check(%s) error = %q", tt.path, got.Error.String)
+			}
+			if !got.StatusCode.Valid || got.StatusCode.Int32 != tt.wantStatus {
+				t.Errorf("check(%s) status = %+v, want %d", tt.path, got.StatusCode, tt.wantStatus)
+			}
+			if got.Broken != tt.wantBroken {
+				t.Errorf("check(%s) broken = %t, want %t", tt.path, got.Broken, tt.wantBroken)
+			}
+			if strings.Join(methods, ",") != strings.Join(tt.wantMethods, ",") {
+				t.Errorf("check(%s) made %v, want %v", tt.path, methods, tt.wantMethods)
+			}
+			if got.CheckedAt.IsZero() {
+				t.Error("CheckedAt is not set")
+			}
+		})
+	}
+}
+
+func TestCheckUnreachable(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	url := srv.URL
+	srv.Close()
+
+	got := newTestChecker(t).check(context.Background(), url)
+	if !got.Broken || !got.Error.Valid || got.StatusCode.Valid {
+		t.Errorf("check of a closed server = %+v, want broken with an error", got)
+	}
+}
+
+func TestCheckRefusesInternalAddresses(t *testing.T) {
+	requested := false
+	srv := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		requested = true
+	}))
+	t.Cleanup(srv.Close)
+
+	c := NewChecker(nil, config.HealthCheckConfig{Timeout: 5 * time.Second})
+	got := c.check(context.Background(), srv.URL)
+
+	if requested {
+		t.Error("checker connected to a loopback address")
+	}
+	if !got.Broken || !strings.Contains(got.Error.String, errInternalAddress.Error()) {
+		t.Errorf("check of a loopback address = %+v, want broken with %q", got, errInternalAddress)
+	}
+}
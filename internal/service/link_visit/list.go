@@ -0,0 +1,81 @@
+package linkvisit
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"markoni23/url-shortener/internal/model"
+	"markoni23/url-shortener/internal/pagination"
+)
+
+// visitSortColumns maps the sort fields of GetAll to columns.
+var visitSortColumns = map[string]string{
+	"id":         "link_visits.id",
+	"created_at": "link_visits.created_at",
+	"link_id":    "link_visits.link_id",
+	"status":     "link_visits.status",
+	"ip":         "link_visits.ip",
+	"country":    "link_visits.country",
+	"browser":    "link_visits.browser",
+}
+
+// sortedVisitIDs has the filters of CountLinkVisits. sqlc cannot take the
+// ORDER BY column as a parameter, and choosing it with CASE expressions
+// keeps PostgreSQL from using an index for any order, so only this query
+// is built here; the visits themselves are read with GetLinkVisitsByIDs.
+const sortedVisitIDs = `SELECT link_visits.id FROM link_visits
+JOIN links ON links.id = link_visits.link_id
+WHERE links.owner_id = $1
+  AND ($2::text IS NULL OR link_visits.browser = $2::text)
+  AND ($3::text IS NULL OR link_visits.os = $3::text)
+  AND ($4::text IS NULL OR link_visits.device_type = $4::text)
+  AND ($5::boolean IS NULL OR link_visits.is_bot = $5::boolean)
+  AND ($6::text IS NULL OR link_visits.country = $6::text)
+  AND ($7::bigint IS NULL OR link_visits.link_id = $7::bigint)
+  AND ($8::text IS NULL OR link_visits.ip = $8::text)
+  AND ($9::integer IS NULL OR link_visits.status = $9::integer)
+  AND ($10::timestamp IS NULL OR link_visits.created_at >= $10::timestamp)
+  AND ($11::timestamp IS NULL OR link_visits.created_at < $11::timestamp)
+%s
+LIMIT $12
+OFFSET $13`
+
+// listVisitIDs returns the ids of one range of the owner's visits in the
+// requested order.
+func (s *service) listVisitIDs(ctx context.Context, ownerID int64, filter model.VisitFilter, sort pagination.Sort, limit, offset int64) ([]int64, error) {
+	order, err := sort.OrderBy(visitSortColumns)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.QueryContext(ctx, fmt.Sprintf(sortedVisitIDs, order),
+		sql.NullInt64{Int64: ownerID, Valid: true},
+		toNullString(filter.Browser),
+		toNullString(filter.OS),
+		toNullString(filter.DeviceType),
+		toNullBool(filter.IsBot),
+		toNullString(filter.Country),
+		toNullInt64(filter.LinkID),
+		toNullString(filter.IP),
+		toNullInt32(filter.Status),
+		toNullTime(filter.CreatedFrom),
+		toNullTime(filter.CreatedTo),
+		limit,
+		offset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
@@ -0,0 +1,314 @@
+package linkvisit
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"markoni23/url-shortener/internal/config"
+	"markoni23/url-shortener/internal/geoip"
+	"markoni23/url-shortener/internal/tracing"
+	"markoni23/url-shortener/internal/useragent"
+
+	"github.com/lib/pq"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+const flushTimeout = 10 * time.Second
+
+var visitColumns = []string{
+	"link_id", "ip", "user_agent", "referer", "status", "created_at",
+	"browser", "browser_version", "os", "device_type", "is_bot",
+	"country", "region", "city", "asn", "as_org",
+}
+
+// visitEvent is the raw data captured on the redirect path. Parsing the
+// user agent and the GeoIP lookup are deferred to the recorder workers.
+type visitEvent struct {
+	LinkID    int64
+	Ip        string
+	UserAgent string
+	Referer   string
+	Status    int32
+	CreatedAt time.Time
+}
+
+type RecorderStats struct {
+	Queued  int
+	Written int64
+	Failed  int64
+	Dropped int64
+}
+
+// Recorder writes visits off the request path. Events go into a bounded
+// queue and are flushed by a pool of workers with COPY, either when a batch
+// is full or when the flush interval elapses. When the queue is full, or
+// the recorder is closing, new events are dropped rather than slowing down
+// redirects.
+type Recorder struct {
+	db            *sql.DB
+	geo           geoip.Resolver
+	queue         chan visitEvent
+	batchSize     int
+	flushInterval time.Duration
+	workers       int
+
+	// mu guards closed, so that Enqueue never sends on the closed queue.
+	mu     sync.RWMutex
+	closed bool
+	// abortCtx is cancelled when Close runs out of time. It cancels the
+	// write in progress and makes the workers drop the rest of the queue.
+	abortCtx context.Context
+	abort    context.CancelFunc
+	wg       sync.WaitGroup
+
+	written atomic.Int64
+	failed  atomic.Int64
+	dropped atomic.Int64
+}
+
+func NewRecorder(db *sql.DB, geo geoip.Resolver, cfg config.VisitsConfig) *Recorder {
+	abortCtx, abort := context.WithCancel(context.Background())
+	return &Recorder{
+		db:            db,
+		geo:           geo,
+		queue:         make(chan visitEvent, cfg.QueueSize),
+		batchSize:     cfg.BatchSize,
+		flushInterval: cfg.FlushInterval,
+		workers:       cfg.Workers,
+		abortCtx:      abortCtx,
+		abort:         abort,
+	}
+}
+
+func (r *Recorder) Start() {
+	for range r.workers {
+		r.wg.Add(1)
+		go r.work()
+	}
+}
+
+// Enqueue never blocks. It reports false when the event had to be dropped.
+func (r *Recorder) Enqueue(event visitEvent) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if r.closed {
+		r.dropped.Add(1)
+		return false
+	}
+
+	select {
+	case r.queue <- event:
+		return true
+	default:
+		if n := r.dropped.Add(1); n == 1 || n%1000 == 0 {
+			slog.Warn("visit recorder queue is full", "dropped", n)
+		}
+		return false
+	}
+}
+
+func (r *Recorder) Stats() RecorderStats {
+	return RecorderStats{
+		Queued:  len(r.queue),
+		Written: r.written.Load(),
+		Failed:  r.failed.Load(),
+		Dropped: r.dropped.Load(),
+	}
+}
+
+// Close stops accepting events and waits until the queue is drained. When
+// ctx is done first, the write in progress is cancelled and the remaining
+// events are dropped. Close returns only once every worker has stopped, so
+// the database and the GeoIP readers may be closed afterwards.
+func (r *Recorder) Close(ctx context.Context) error {
+	r.mu.Lock()
+	if !r.closed {
+		r.closed = true
+		close(r.queue)
+	}
+	r.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		r.wg.Wait()
+		close(done)
+	}()
+
+	var err error
+	select {
+	case <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+		r.abort()
+		<-done
+	}
+	r.abort()
+
+	stats := r.Stats()
+	slog.Info("visit recorder stopped",
+		"written", stats.Written, "failed", stats.Failed, "dropped", stats.Dropped)
+	return err
+}
+
+func (r *Recorder) work() {
+	defer r.wg.Done()
+
+	ticker := time.NewTicker(r.flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]visitEvent, 0, r.batchSize)
+	for {
+		select {
+		case <-r.abortCtx.Done():
+			dropped := len(batch)
+			for range r.queue {
+				dropped++
+			}
+			r.dropped.Add(int64(dropped))
+			return
+		case event, ok := <-r.queue:
+			if !ok {
+				r.flush(batch)
+				return
+			}
+			batch = append(batch, event)
+			if len(batch) >= r.batchSize {
+				r.flush(batch)
+				batch = batch[:0]
+			}
+		case <-ticker.C:
+			r.flush(batch)
+			batch = batch[:0]
+		}
+	}
+}
+
+func (r *Recorder) flush(batch []visitEvent) {
+	if len(batch) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.abortCtx, flushTimeout)
+	defer cancel()
+
+	// Flushes run outside any request, so each one is the root of a trace
+	// holding its database statements.
+	ctx, span := tracing.Start(ctx, "VisitRecorder.flush", attribute.Int("visits.count", len(batch)))
+	written, err := r.copy(ctx, batch)
+	if isForeignKeyViolation(err) {
+		// A link was deleted while its visits were being inserted; the
+		// retry leaves them out.
+		written, err = r.copy(ctx, batch)
+	}
+	tracing.End(span, err)
+	if err != nil {
+		r.failed.Add(int64(len(batch)))
+		slog.Error("visit recorder failed to write visits", "count", len(batch), "error", err)
+		return
+	}
+	r.written.Add(written)
+	if skipped := int64(len(batch)) - written; skipped > 0 {
+		r.dropped.Add(skipped)
+		slog.Debug("visit recorder dropped visits of deleted links", "count", skipped)
+	}
+}
+
+var (
+	stageVisits = "CREATE TEMP TABLE link_visits_staging ON COMMIT DROP AS SELECT " +
+		strings.Join(visitColumns, ", ") + " FROM link_visits WITH NO DATA"
+	insertStagedVisits = "INSERT INTO link_visits (" + strings.Join(visitColumns, ", ") + ") SELECT " +
+		strings.Join(visitColumns, ", ") + " FROM link_visits_staging" +
+		" WHERE EXISTS (SELECT 1 FROM links WHERE links.id = link_visits_staging.link_id)"
+)
+
+// copy writes the batch with COPY into a staging table and moves the rows
+// into link_visits from there, leaving out the visits of links deleted
+// since they were enqueued. It returns the number of visits written.
+func (r *Recorder) copy(ctx context.Context, batch []visitEvent) (int64, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	if _, err := tx.ExecContext(ctx, stageVisits); err != nil {
+		return 0, err
+	}
+
+	stmt, err := tx.PrepareContext(ctx, pq.CopyIn("link_visits_staging", visitColumns...))
+	if err != nil {
+		return 0, err
+	}
+
+	for _, event := range batch {
+		if _, err := stmt.ExecContext(ctx, r.row(event)...); err != nil {
+			_ = stmt.Close()
+			return 0, err
+		}
+	}
+	if _, err := stmt.ExecContext(ctx); err != nil {
+		_ = stmt.Close()
+		return 0, err
+	}
+	if err := stmt.Close(); err != nil {
+		return 0, err
+	}
+
+	res, err := tx.ExecContext(ctx, insertStagedVisits)
+	if err != nil {
+		return 0, err
+	}
+	written, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	return written, tx.Commit()
+}
+
+func isForeignKeyViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23503"
+}
+
+// row enriches the event and returns its values in visitColumns order.
+func (r *Recorder) row(event visitEvent) []any {
+	ua := useragent.Parse(event.UserAgent)
+
+	loc, err := r.geo.Lookup(net.ParseIP(event.Ip))
+	if err != nil {
+		slog.Warn("geoip lookup failed", "ip", event.Ip, "error", err)
+	}
+
+	return []any{
+		event.LinkID,
+		event.Ip,
+		event.UserAgent,
+		event.Referer,
+		event.Status,
+		event.CreatedAt.UTC(),
+		nullString(ua.Browser),
+		nullString(ua.BrowserVersion),
+		nullString(ua.OS),
+		ua.DeviceType,
+		ua.IsBot,
+		nullString(loc.Country),
+		nullString(loc.Region),
+		nullString(loc.City),
+		sql.NullInt64{Int64: loc.ASN, Valid: loc.ASN != 0},
+		nullString(loc.ASOrg),
+	}
+}
+
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
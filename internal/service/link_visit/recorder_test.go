@@ -0,0 +1,149 @@
+package linkvisit
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"markoni23/url-shortener/internal/config"
+	"markoni23/url-shortener/internal/geoip"
+)
+
+// newTestRecorder returns a recorder whose database refuses connections,
+// so every write fails fast.
+func newTestRecorder(t *testing.T) *Recorder {
+	t.Helper()
+
+	db, err := sql.Open("postgres", "host=127.0.0.1 port=1 sslmode=disable connect_timeout=1")
+	if err != nil {
+		t.Fatalf("sql.Open: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+
+	return NewRecorder(db, geoip.NopResolver{}, config.VisitsConfig{
+		QueueSize:     100,
+		BatchSize:     100,
+		FlushInterval: time.Hour,
+		Workers:       2,
+	})
+}
+
+func TestRecorderDropsEventsAfterClose(t *testing.T) {
+	r := newTestRecorder(t)
+	r.Start()
+
+	if err := r.Close(context.Background()); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if r.Enqueue(visitEvent{LinkID: 1}) {
+		t.Error("Enqueue after Close accepted the event")
+	}
+	if got := r.Stats().Dropped; got != 1 {
+		t.Errorf("Dropped = %d, want 1", got)
+	}
+}
+
+func TestRecorderEnqueueDuringClose(t *testing.T) {
+	r := newTestRecorder(t)
+	r.Start()
+
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Go(func() {
+			for range 50 {
+				r.Enqueue(visitEvent{LinkID: 1})
+			}
+		})
+	}
+	if err := r.Close(context.Background()); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	wg.Wait()
+
+	stats := r.Stats()
+	if total := stats.Written + stats.Failed + stats.Dropped; total != 200 {
+		t.Errorf("stats = %+v, want 200 events accounted for", stats)
+	}
+}
+
+func TestRecorderCloseTimeoutWaitsForWorkers(t *testing.T) {
+	r := newTestRecorder(t)
+	r.Start()
+	for range 10 {
+		r.Enqueue(visitEvent{LinkID: 1})
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_ = r.Close(ctx)
+
+	// Close returned, so the workers are done and the counters final.
+	stats := r.Stats()
+	if stats.Written != 0 || stats.Failed+stats.Dropped != 10 {
+		t.Errorf("stats = %+v, want 10 events failed or dropped", stats)
+	}
+}
+
+// TestRecorderSkipsDeletedLinks needs a migrated database, given by
+// TEST_DATABASE_URL.
+func TestRecorderSkipsDeletedLinks(t *testing.T) {
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+	db, err := sql.Open("postgres", url)
+	if err != nil {
+		t.Fatalf("sql.Open: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+
+	ctx := context.Background()
+	createLink := func(name string) int64 {
+		t.Helper()
+		var id int64
+		err := db.QueryRowContext(ctx,
+			"INSERT INTO links (original_url, short_name) VALUES ('https://example.com', $1) RETURNING id",
+			fmt.Sprintf("%s-%d", name, time.Now().UnixNano()),
+		).Scan(&id)
+		if err != nil {
+			t.Fatalf("insert link: %v", err)
+		}
+		t.Cleanup(func() { _, _ = db.ExecContext(ctx, "DELETE FROM links WHERE id = $1", id) })
+		return id
+	}
+	kept := createLink("recorder-kept")
+	deleted := createLink("recorder-deleted")
+
+	r := NewRecorder(db, geoip.NopResolver{}, config.VisitsConfig{
+		QueueSize:     100,
+		BatchSize:     100,
+		FlushInterval: time.Hour,
+		Workers:       1,
+	})
+	r.Start()
+	for _, id := range []int64{kept, deleted, kept} {
+		r.Enqueue(visitEvent{LinkID: id, Ip: "192.0.2.1", Status: 302, CreatedAt: time.Now()})
+	}
+	if _, err := db.ExecContext(ctx, "DELETE FROM links WHERE id = $1", deleted); err != nil {
+		t.Fatalf("delete link: %v", err)
+	}
+	if err := r.Close(ctx); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	stats := r.Stats()
+	if stats.Written != 2 || stats.Failed != 0 || stats.Dropped != 1 {
+		t.Errorf("stats = %+v, want 2 written and 1 dropped", stats)
+	}
+	var visits int
+	if err := db.QueryRowContext(ctx, "SELECT COUNT(1) FROM link_visits WHERE link_id = $1", kept).Scan(&visits); err != nil {
+		t.Fatalf("count visits: %v", err)
+	}
+	if visits != 2 {
+		t.Errorf("link has %d visits, want 2", visits)
+	}
+}
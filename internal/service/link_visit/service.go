@@ -3,68 +3,136 @@ package linkvisit
 import (
 	"context"
 	"database/sql"
-	"errors"
 	"markoni23/url-shortener/internal/model"
+	"markoni23/url-shortener/internal/pagination"
 	"markoni23/url-shortener/internal/sqlcdb"
+	"markoni23/url-shortener/internal/useragent"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
 type service struct {
-	queries *sqlcdb.Queries
+	db       *sql.DB
+	queries  *sqlcdb.Queries
+	recorder *Recorder
 }
 
-func NewService(queries *sqlcdb.Queries) *service {
+func NewService(db *sql.DB, queries *sqlcdb.Queries, recorder *Recorder) *service {
 	return &service{
-		queries: queries,
+		db:       db,
+		queries:  queries,
+		recorder: recorder,
 	}
 }
 
-func (s *service) Count(ctx context.Context) (int64, error) {
-	return s.queries.CountLinkVisits(ctx)
+func (s *service) Count(ctx context.Context, ownerID int64, filter model.VisitFilter) (int64, error) {
+	return s.queries.CountLinkVisits(ctx, sqlcdb.CountLinkVisitsParams{
+		OwnerID:     sql.NullInt64{Int64: ownerID, Valid: true},
+		Browser:     toNullString(filter.Browser),
+		Os:          toNullString(filter.OS),
+		DeviceType:  toNullString(filter.DeviceType),
+		IsBot:       toNullBool(filter.IsBot),
+		Country:     toNullString(filter.Country),
+		LinkID:      toNullInt64(filter.LinkID),
+		Ip:          toNullString(filter.IP),
+		Status:      toNullInt32(filter.Status),
+		CreatedFrom: toNullTime(filter.CreatedFrom),
+		CreatedTo:   toNullTime(filter.CreatedTo),
+	})
 }
 
-func (s *service) GetAll(ctx context.Context, from, to int64) ([]model.LinkVisit, error) {
+func (s *service) GetAll(ctx context.Context, ownerID, from, to int64, filter model.VisitFilter, sort pagination.Sort) ([]model.LinkVisit, error) {
 	if from < 0 || to <= 0 {
-		return []model.LinkVisit{}, errors.New("from and to must be greater than zero")
+		return []model.LinkVisit{}, &model.InvalidParamsError{Reason: "from and to must be greater than zero"}
 	}
 
 	if from >= to {
-		return []model.LinkVisit{}, errors.New("from must be less than to")
+		return []model.LinkVisit{}, &model.InvalidParamsError{Reason: "from must be less than to"}
 	}
 
-	limit := to - from + 1
-	offset := from
-	visits, err := s.queries.GetAllLinkVisits(ctx, sqlcdb.GetAllLinkVisitsParams{
-		Limit:  int32(limit),
-		Offset: int32(offset),
-	})
+	ids, err := s.listVisitIDs(ctx, ownerID, filter, sort, to-from+1, from)
+	if err != nil {
+		return []model.LinkVisit{}, err
+	}
+	visits, err := s.queries.GetLinkVisitsByIDs(ctx, ids)
 	if err != nil {
 		return []model.LinkVisit{}, err
 	}
 
-	res := make([]model.LinkVisit, len(visits))
+	byID := make(map[int64]sqlcdb.LinkVisit, len(visits))
+	for _, raw := range visits {
+		byID[raw.ID] = raw
+	}
+
+	// A visit deleted with its link between the two queries is left out.
+	res := make([]model.LinkVisit, 0, len(ids))
+	for _, id := range ids {
+		if raw, ok := byID[id]; ok {
+			res = append(res, s.rawToModel(raw))
+		}
+	}
+	return res, nil
+}
+
+// GetPage returns up to page.Limit visits older than the cursor, newest
+// first, and the cursor of the next page or nil when this is the last one.
+func (s *service) GetPage(ctx context.Context, ownerID int64, page pagination.Params, filter model.VisitFilter) ([]model.LinkVisit, *pagination.Cursor, error) {
+	params := sqlcdb.GetLinkVisitsAfterParams{
+		OwnerID:     sql.NullInt64{Int64: ownerID, Valid: true},
+		Browser:     toNullString(filter.Browser),
+		Os:          toNullString(filter.OS),
+		DeviceType:  toNullString(filter.DeviceType),
+		IsBot:       toNullBool(filter.IsBot),
+		Country:     toNullString(filter.Country),
+		LinkID:      toNullInt64(filter.LinkID),
+		Ip:          toNullString(filter.IP),
+		Status:      toNullInt32(filter.Status),
+		CreatedFrom: toNullTime(filter.CreatedFrom),
+		CreatedTo:   toNullTime(filter.CreatedTo),
+		// One extra row tells whether there is a next page.
+		Limit: int32(page.Limit + 1),
+	}
+	if page.After != nil {
+		params.AfterCreatedAt = sql.NullTime{Time: page.After.CreatedAt, Valid: true}
+		params.AfterID = page.After.ID
+	}
+
+	visits, err := s.queries.GetLinkVisitsAfter(ctx, params)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var next *pagination.Cursor
+	if len(visits) > page.Limit {
+		visits = visits[:page.Limit]
+		last := visits[page.Limit-1]
+		next = &pagination.Cursor{CreatedAt: last.CreatedAt.Time, ID: last.ID}
+	}
 
+	res := make([]model.LinkVisit, len(visits))
 	for i, raw := range visits {
 		res[i] = s.rawToModel(raw)
 	}
-	return res, nil
+	return res, next, nil
 }
 
+// Visit redirects to the destination of the link. Links with max_visits
+// claim one redirect from the counter on the links row first, so that the
+// limit holds for concurrent visitors. Bots do not use up redirects.
 func (s *service) Visit(ctx *gin.Context, link model.Link) error {
-	userAgent := ctx.GetHeader("User-Agent")
-	referer := ctx.GetHeader("Referer")
-	params := sqlcdb.CreateLinkVisitParams{
-		LinkID:    link.ID,
-		Ip:        ctx.ClientIP(),
-		UserAgent: sql.NullString{String: userAgent, Valid: true},
-		Referer:   sql.NullString{String: referer, Valid: true},
-		Status:    http.StatusFound,
+	if link.MaxVisits != nil && !useragent.Parse(ctx.GetHeader("User-Agent")).IsBot {
+		claimed, err := s.queries.ClaimRedirect(ctx, link.ID)
+		if err != nil {
+			return err
+		}
+		if claimed == 0 {
+			return &model.LinkExpiredError{}
+		}
 	}
 
-	_, err := s.queries.CreateLinkVisit(ctx, params)
-	if err != nil {
+	if err := s.record(ctx, link, http.StatusFound); err != nil {
 		return err
 	}
 
@@ -73,6 +141,26 @@ func (s *service) Visit(ctx *gin.Context, link model.Link) error {
 	return nil
 }
 
+// RecordFailedUnlock logs a wrong password attempt on a protected link so
+// that brute-force attempts show up in the visits list.
+func (s *service) RecordFailedUnlock(ctx *gin.Context, link model.Link) error {
+	return s.record(ctx, link, http.StatusUnauthorized)
+}
+
+// record hands the visit over to the background recorder, so a slow or
+// unavailable database does not delay the redirect.
+func (s *service) record(ctx *gin.Context, link model.Link, status int32) error {
+	s.recorder.Enqueue(visitEvent{
+		LinkID:    link.ID,
+		Ip:        ctx.ClientIP(),
+		UserAgent: ctx.GetHeader("User-Agent"),
+		Referer:   ctx.GetHeader("Referer"),
+		Status:    status,
+		CreatedAt: time.Now(),
+	})
+	return nil
+}
+
 func (s *service) rawToModel(raw sqlcdb.LinkVisit) model.LinkVisit {
 	return model.LinkVisit{
 		ID:        raw.ID,
@@ -82,5 +170,51 @@ func (s *service) rawToModel(raw sqlcdb.LinkVisit) model.LinkVisit {
 		Referer:   &raw.Referer.String,
 		Status:    int64(raw.Status),
 		CreatedAt: raw.CreatedAt.Time,
+
+		Browser:        raw.Browser.String,
+		BrowserVersion: raw.BrowserVersion.String,
+		OS:             raw.Os.String,
+		DeviceType:     raw.DeviceType.String,
+		IsBot:          raw.IsBot,
+		Country:        raw.Country.String,
+		Region:         raw.Region.String,
+		City:           raw.City.String,
+		ASN:            raw.Asn.Int64,
+		ASOrg:          raw.AsOrg.String,
+	}
+}
+
+func toNullString(v *string) sql.NullString {
+	if v == nil {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: *v, Valid: true}
+}
+
+func toNullInt64(v *int64) sql.NullInt64 {
+	if v == nil {
+		return sql.NullInt64{}
+	}
+	return sql.NullInt64{Int64: *v, Valid: true}
+}
+
+func toNullInt32(v *int32) sql.NullInt32 {
+	if v == nil {
+		return sql.NullInt32{}
+	}
+	return sql.NullInt32{Int32: *v, Valid: true}
+}
+
+func toNullTime(t *time.Time) sql.NullTime {
+	if t == nil {
+		return sql.NullTime{}
+	}
+	return sql.NullTime{Time: t.UTC(), Valid: true}
+}
+
+func toNullBool(v *bool) sql.NullBool {
+	if v == nil {
+		return sql.NullBool{}
 	}
+	return sql.NullBool{Bool: *v, Valid: true}
 }
@@ -0,0 +1,155 @@
+package linkvisit
+
+import (
+	"context"
+	"time"
+
+	"markoni23/url-shortener/internal/model"
+	"markoni23/url-shortener/internal/sqlcdb"
+)
+
+const (
+	IntervalHour = "hour"
+	IntervalDay  = "day"
+	IntervalWeek = "week"
+
+	statsTopLimit   = 10
+	statsMaxBuckets = 1000
+)
+
+// Stats aggregates the redirects of a link in [from, to) into buckets of the
+// given interval. Buckets without visits are included with zero counts so
+// the series can be charted directly.
+func (s *service) Stats(ctx context.Context, linkID int64, from, to time.Time, interval string) (model.LinkStats, error) {
+	from, to = from.UTC(), to.UTC()
+	if !from.Before(to) {
+		return model.LinkStats{}, &model.InvalidParamsError{Reason: "from must be before to"}
+	}
+
+	start := truncateToInterval(from, interval)
+	if start.IsZero() {
+		return model.LinkStats{}, &model.InvalidParamsError{Reason: "interval must be one of hour, day, week"}
+	}
+
+	var buckets []time.Time
+	for t := start; t.Before(to); t = nextInterval(t, interval) {
+		if len(buckets) == statsMaxBuckets {
+			return model.LinkStats{}, &model.InvalidParamsError{Reason: "too many buckets, use a shorter range or a larger interval"}
+		}
+		buckets = append(buckets, t)
+	}
+
+	summary, err := s.queries.GetLinkVisitsSummary(ctx, sqlcdb.GetLinkVisitsSummaryParams{
+		LinkID:   linkID,
+		FromTime: from,
+		ToTime:   to,
+	})
+	if err != nil {
+		return model.LinkStats{}, err
+	}
+
+	seriesRaw, err := s.queries.GetLinkVisitsTimeSeries(ctx, sqlcdb.GetLinkVisitsTimeSeriesParams{
+		BucketSize: interval,
+		LinkID:     linkID,
+		FromTime:   from,
+		ToTime:     to,
+	})
+	if err != nil {
+		return model.LinkStats{}, err
+	}
+
+	referers, err := s.queries.GetLinkTopReferers(ctx, sqlcdb.GetLinkTopReferersParams{
+		LinkID:   linkID,
+		FromTime: from,
+		ToTime:   to,
+		TopLimit: statsTopLimit,
+	})
+	if err != nil {
+		return model.LinkStats{}, err
+	}
+
+	userAgents, err := s.queries.GetLinkTopUserAgents(ctx, sqlcdb.GetLinkTopUserAgentsParams{
+		LinkID:   linkID,
+		FromTime: from,
+		ToTime:   to,
+		TopLimit: statsTopLimit,
+	})
+	if err != nil {
+		return model.LinkStats{}, err
+	}
+
+	countries, err := s.queries.GetLinkTopCountries(ctx, sqlcdb.GetLinkTopCountriesParams{
+		LinkID:   linkID,
+		FromTime: from,
+		ToTime:   to,
+		TopLimit: statsTopLimit,
+	})
+	if err != nil {
+		return model.LinkStats{}, err
+	}
+
+	byBucket := make(map[time.Time]sqlcdb.GetLinkVisitsTimeSeriesRow, len(seriesRaw))
+	for _, row := range seriesRaw {
+		byBucket[row.Bucket.UTC()] = row
+	}
+
+	stats := model.LinkStats{
+		LinkID:        linkID,
+		From:          from,
+		To:            to,
+		Interval:      interval,
+		Visits:        summary.Visits,
+		UniqueIps:     summary.UniqueIps,
+		Series:        make([]model.StatsBucket, len(buckets)),
+		TopReferers:   make([]model.StatsCount, len(referers)),
+		TopUserAgents: make([]model.StatsCount, len(userAgents)),
+		TopCountries:  make([]model.StatsCount, len(countries)),
+	}
+	for i, bucket := range buckets {
+		row := byBucket[bucket]
+		stats.Series[i] = model.StatsBucket{
+			Time:      bucket,
+			Visits:    row.Visits,
+			UniqueIps: row.UniqueIps,
+		}
+	}
+	for i, row := range referers {
+		stats.TopReferers[i] = model.StatsCount{Value: row.Value, Visits: row.Visits}
+	}
+	for i, row := range userAgents {
+		stats.TopUserAgents[i] = model.StatsCount{Value: row.Value, Visits: row.Visits}
+	}
+	for i, row := range countries {
+		stats.TopCountries[i] = model.StatsCount{Value: row.Value, Visits: row.Visits}
+	}
+
+	return stats, nil
+}
+
+// truncateToInterval mirrors Postgres date_trunc for UTC times. Weeks start
+// on Monday. It returns the zero time for an unknown interval.
+func truncateToInterval(t time.Time, interval string) time.Time {
+	switch interval {
+	case IntervalHour:
+		return t.Truncate(time.Hour)
+	case IntervalDay:
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	case IntervalWeek:
+		day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+		offset := (int(day.Weekday()) + 6) % 7
+		return day.AddDate(0, 0, -offset)
+	default:
+		return time.Time{}
+	}
+}
+
+func nextInterval(t time.Time, interval string) time.Time {
+	switch interval {
+	case IntervalHour:
+		return t.Add(time.Hour)
+	case IntervalDay:
+		return t.AddDate(0, 0, 1)
+	default:
+		return t.AddDate(0, 0, 7)
+	}
+}
@@ -8,31 +8,89 @@ package sqlcdb
 import (
 	"context"
 	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
 )
 
 const countLinkVisits = `-- name: CountLinkVisits :one
 SELECT COUNT(1) FROM link_visits
+JOIN links ON links.id = link_visits.link_id
+WHERE links.owner_id = $1
+  AND ($2::text IS NULL OR link_visits.browser = $2::text)
+  AND ($3::text IS NULL OR link_visits.os = $3::text)
+  AND ($4::text IS NULL OR link_visits.device_type = $4::text)
+  AND ($5::boolean IS NULL OR link_visits.is_bot = $5::boolean)
+  AND ($6::text IS NULL OR link_visits.country = $6::text)
+  AND ($7::bigint IS NULL OR link_visits.link_id = $7::bigint)
+  AND ($8::text IS NULL OR link_visits.ip = $8::text)
+  AND ($9::integer IS NULL OR link_visits.status = $9::integer)
+  AND ($10::timestamp IS NULL OR link_visits.created_at >= $10::timestamp)
+  AND ($11::timestamp IS NULL OR link_visits.created_at < $11::timestamp)
 `
 
-func (q *Queries) CountLinkVisits(ctx context.Context) (int64, error) {
-	row := q.db.QueryRowContext(ctx, countLinkVisits)
+type CountLinkVisitsParams struct {
+	OwnerID     sql.NullInt64
+	Browser     sql.NullString
+	Os          sql.NullString
+	DeviceType  sql.NullString
+	IsBot       sql.NullBool
+	Country     sql.NullString
+	LinkID      sql.NullInt64
+	Ip          sql.NullString
+	Status      sql.NullInt32
+	CreatedFrom sql.NullTime
+	CreatedTo   sql.NullTime
+}
+
+func (q *Queries) CountLinkVisits(ctx context.Context, arg CountLinkVisitsParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countLinkVisits,
+		arg.OwnerID,
+		arg.Browser,
+		arg.Os,
+		arg.DeviceType,
+		arg.IsBot,
+		arg.Country,
+		arg.LinkID,
+		arg.Ip,
+		arg.Status,
+		arg.CreatedFrom,
+		arg.CreatedTo,
+	)
 	var count int64
 	err := row.Scan(&count)
 	return count, err
 }
 
 const createLinkVisit = `-- name: CreateLinkVisit :one
-INSERT INTO link_visits (link_id, ip, user_agent, referer, status)
-VALUES ($1, $2, $3, $4, $5)
-RETURNING id, link_id, ip, user_agent, referer, status, created_at
+INSERT INTO link_visits (
+    link_id, ip, user_agent, referer, status,
+    browser, browser_version, os, device_type, is_bot,
+    country, region, city, asn, as_org
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15
+)
+RETURNING id, link_id, ip, user_agent, referer, status, created_at,
+          browser, browser_version, os, device_type, is_bot,
+          country, region, city, asn, as_org
 `
 
 type CreateLinkVisitParams struct {
-	LinkID    int64
-	Ip        string
-	UserAgent sql.NullString
-	Referer   sql.NullString
-	Status    int32
+	LinkID         int64
+	Ip             string
+	UserAgent      sql.NullString
+	Referer        sql.NullString
+	Status         int32
+	Browser        sql.NullString
+	BrowserVersion sql.NullString
+	Os             sql.NullString
+	DeviceType     sql.NullString
+	IsBot          bool
+	Country        sql.NullString
+	Region         sql.NullString
+	City           sql.NullString
+	Asn            sql.NullInt64
+	AsOrg          sql.NullString
 }
 
 func (q *Queries) CreateLinkVisit(ctx context.Context, arg CreateLinkVisitParams) (LinkVisit, error) {
@@ -42,6 +100,16 @@ func (q *Queries) CreateLinkVisit(ctx context.Context, arg CreateLinkVisitParams
 		arg.UserAgent,
 		arg.Referer,
 		arg.Status,
+		arg.Browser,
+		arg.BrowserVersion,
+		arg.Os,
+		arg.DeviceType,
+		arg.IsBot,
+		arg.Country,
+		arg.Region,
+		arg.City,
+		arg.Asn,
+		arg.AsOrg,
 	)
 	var i LinkVisit
 	err := row.Scan(
@@ -52,24 +120,239 @@ func (q *Queries) CreateLinkVisit(ctx context.Context, arg CreateLinkVisitParams
 		&i.Referer,
 		&i.Status,
 		&i.CreatedAt,
+		&i.Browser,
+		&i.BrowserVersion,
+		&i.Os,
+		&i.DeviceType,
+		&i.IsBot,
+		&i.Country,
+		&i.Region,
+		&i.City,
+		&i.Asn,
+		&i.AsOrg,
 	)
 	return i, err
 }
 
-const getAllLinkVisits = `-- name: GetAllLinkVisits :many
-SELECT id, link_id, ip, user_agent, referer, status, created_at
+const getLinkTopCountries = `-- name: GetLinkTopCountries :many
+SELECT COALESCE(country, '')::text AS value, COUNT(1) AS visits
 FROM link_visits
-ORDER BY created_at DESC
-LIMIT $1 OFFSET $2
+WHERE link_id = $1
+  AND status = 302
+  AND is_bot = FALSE
+  AND created_at >= $2::timestamp
+  AND created_at < $3::timestamp
+GROUP BY value
+ORDER BY visits DESC, value
+LIMIT $4
+`
+
+type GetLinkTopCountriesParams struct {
+	LinkID   int64
+	FromTime time.Time
+	ToTime   time.Time
+	TopLimit int32
+}
+
+type GetLinkTopCountriesRow struct {
+	Value  string
+	Visits int64
+}
+
+func (q *Queries) GetLinkTopCountries(ctx context.Context, arg GetLinkTopCountriesParams) ([]GetLinkTopCountriesRow, error) {
+	rows, err := q.db.QueryContext(ctx, getLinkTopCountries,
+		arg.LinkID,
+		arg.FromTime,
+		arg.ToTime,
+		arg.TopLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetLinkTopCountriesRow
+	for rows.Next() {
+		var i GetLinkTopCountriesRow
+		if err := rows.Scan(&i.Value, &i.Visits); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getLinkTopReferers = `-- name: GetLinkTopReferers :many
+SELECT COALESCE(NULLIF(referer, ''), '(direct)')::text AS value, COUNT(1) AS visits
+FROM link_visits
+WHERE link_id = $1
+  AND status = 302
+  AND is_bot = FALSE
+  AND created_at >= $2::timestamp
+  AND created_at < $3::timestamp
+GROUP BY value
+ORDER BY visits DESC, value
+LIMIT $4
+`
+
+type GetLinkTopReferersParams struct {
+	LinkID   int64
+	FromTime time.Time
+	ToTime   time.Time
+	TopLimit int32
+}
+
+type GetLinkTopReferersRow struct {
+	Value  string
+	Visits int64
+}
+
+func (q *Queries) GetLinkTopReferers(ctx context.Context, arg GetLinkTopReferersParams) ([]GetLinkTopReferersRow, error) {
+	rows, err := q.db.QueryContext(ctx, getLinkTopReferers,
+		arg.LinkID,
+		arg.FromTime,
+		arg.ToTime,
+		arg.TopLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetLinkTopReferersRow
+	for rows.Next() {
+		var i GetLinkTopReferersRow
+		if err := rows.Scan(&i.Value, &i.Visits); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getLinkTopUserAgents = `-- name: GetLinkTopUserAgents :many
+SELECT COALESCE(NULLIF(user_agent, ''), '(unknown)')::text AS value, COUNT(1) AS visits
+FROM link_visits
+WHERE link_id = $1
+  AND status = 302
+  AND is_bot = FALSE
+  AND created_at >= $2::timestamp
+  AND created_at < $3::timestamp
+GROUP BY value
+ORDER BY visits DESC, value
+LIMIT $4
 `
 
-type GetAllLinkVisitsParams struct {
-	Limit  int32
-	Offset int32
+type GetLinkTopUserAgentsParams struct {
+	LinkID   int64
+	FromTime time.Time
+	ToTime   time.Time
+	TopLimit int32
 }
 
-func (q *Queries) GetAllLinkVisits(ctx context.Context, arg GetAllLinkVisitsParams) ([]LinkVisit, error) {
-	rows, err := q.db.QueryContext(ctx, getAllLinkVisits, arg.Limit, arg.Offset)
+type GetLinkTopUserAgentsRow struct {
+	Value  string
+	Visits int64
+}
+
+func (q *Queries) GetLinkTopUserAgents(ctx context.Context, arg GetLinkTopUserAgentsParams) ([]GetLinkTopUserAgentsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getLinkTopUserAgents,
+		arg.LinkID,
+		arg.FromTime,
+		arg.ToTime,
+		arg.TopLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetLinkTopUserAgentsRow
+	for rows.Next() {
+		var i GetLinkTopUserAgentsRow
+		if err := rows.Scan(&i.Value, &i.Visits); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getLinkVisitsAfter = `-- name: GetLinkVisitsAfter :many
+SELECT link_visits.id, link_visits.link_id, link_visits.ip, link_visits.user_agent,
+       link_visits.referer, link_visits.status, link_visits.created_at,
+       link_visits.browser, link_visits.browser_version, link_visits.os,
+       link_visits.device_type, link_visits.is_bot,
+       link_visits.country, link_visits.region, link_visits.city,
+       link_visits.asn, link_visits.as_org
+FROM link_visits
+JOIN links ON links.id = link_visits.link_id
+WHERE links.owner_id = $1
+  AND ($2::text IS NULL OR link_visits.browser = $2::text)
+  AND ($3::text IS NULL OR link_visits.os = $3::text)
+  AND ($4::text IS NULL OR link_visits.device_type = $4::text)
+  AND ($5::boolean IS NULL OR link_visits.is_bot = $5::boolean)
+  AND ($6::text IS NULL OR link_visits.country = $6::text)
+  AND ($7::bigint IS NULL OR link_visits.link_id = $7::bigint)
+  AND ($8::text IS NULL OR link_visits.ip = $8::text)
+  AND ($9::integer IS NULL OR link_visits.status = $9::integer)
+  AND ($10::timestamp IS NULL OR link_visits.created_at >= $10::timestamp)
+  AND ($11::timestamp IS NULL OR link_visits.created_at < $11::timestamp)
+  AND ($12::timestamp IS NULL
+    OR (link_visits.created_at, link_visits.id) < ($12::timestamp, $13::bigint))
+ORDER BY link_visits.created_at DESC, link_visits.id DESC
+LIMIT $14
+`
+
+type GetLinkVisitsAfterParams struct {
+	OwnerID        sql.NullInt64
+	Browser        sql.NullString
+	Os             sql.NullString
+	DeviceType     sql.NullString
+	IsBot          sql.NullBool
+	Country        sql.NullString
+	LinkID         sql.NullInt64
+	Ip             sql.NullString
+	Status         sql.NullInt32
+	CreatedFrom    sql.NullTime
+	CreatedTo      sql.NullTime
+	AfterCreatedAt sql.NullTime
+	AfterID        int64
+	Limit          int32
+}
+
+func (q *Queries) GetLinkVisitsAfter(ctx context.Context, arg GetLinkVisitsAfterParams) ([]LinkVisit, error) {
+	rows, err := q.db.QueryContext(ctx, getLinkVisitsAfter,
+		arg.OwnerID,
+		arg.Browser,
+		arg.Os,
+		arg.DeviceType,
+		arg.IsBot,
+		arg.Country,
+		arg.LinkID,
+		arg.Ip,
+		arg.Status,
+		arg.CreatedFrom,
+		arg.CreatedTo,
+		arg.AfterCreatedAt,
+		arg.AfterID,
+		arg.Limit,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -85,6 +368,16 @@ func (q *Queries) GetAllLinkVisits(ctx context.Context, arg GetAllLinkVisitsPara
 			&i.Referer,
 			&i.Status,
 			&i.CreatedAt,
+			&i.Browser,
+			&i.BrowserVersion,
+			&i.Os,
+			&i.DeviceType,
+			&i.IsBot,
+			&i.Country,
+			&i.Region,
+			&i.City,
+			&i.Asn,
+			&i.AsOrg,
 		); err != nil {
 			return nil, err
 		}
@@ -99,8 +392,142 @@ func (q *Queries) GetAllLinkVisits(ctx context.Context, arg GetAllLinkVisitsPara
 	return items, nil
 }
 
+const getLinkVisitsByIDs = `-- name: GetLinkVisitsByIDs :many
+SELECT id, link_id, ip, user_agent, referer, status, created_at,
+       browser, browser_version, os, device_type, is_bot,
+       country, region, city, asn, as_org
+FROM link_visits
+WHERE id = ANY($1::bigint[])
+`
+
+func (q *Queries) GetLinkVisitsByIDs(ctx context.Context, ids []int64) ([]LinkVisit, error) {
+	rows, err := q.db.QueryContext(ctx, getLinkVisitsByIDs, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []LinkVisit
+	for rows.Next() {
+		var i LinkVisit
+		if err := rows.Scan(
+			&i.ID,
+			&i.LinkID,
+			&i.Ip,
+			&i.UserAgent,
+			&i.Referer,
+			&i.Status,
+			&i.CreatedAt,
+			&i.Browser,
+			&i.BrowserVersion,
+			&i.Os,
+			&i.DeviceType,
+			&i.IsBot,
+			&i.Country,
+			&i.Region,
+			&i.City,
+			&i.Asn,
+			&i.AsOrg,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getLinkVisitsSummary = `-- name: GetLinkVisitsSummary :one
+SELECT COUNT(1) AS visits, COUNT(DISTINCT ip) AS unique_ips
+FROM link_visits
+WHERE link_id = $1
+  AND status = 302
+  AND is_bot = FALSE
+  AND created_at >= $2::timestamp
+  AND created_at < $3::timestamp
+`
+
+type GetLinkVisitsSummaryParams struct {
+	LinkID   int64
+	FromTime time.Time
+	ToTime   time.Time
+}
+
+type GetLinkVisitsSummaryRow struct {
+	Visits    int64
+	UniqueIps int64
+}
+
+func (q *Queries) GetLinkVisitsSummary(ctx context.Context, arg GetLinkVisitsSummaryParams) (GetLinkVisitsSummaryRow, error) {
+	row := q.db.QueryRowContext(ctx, getLinkVisitsSummary, arg.LinkID, arg.FromTime, arg.ToTime)
+	var i GetLinkVisitsSummaryRow
+	err := row.Scan(&i.Visits, &i.UniqueIps)
+	return i, err
+}
+
+const getLinkVisitsTimeSeries = `-- name: GetLinkVisitsTimeSeries :many
+SELECT date_trunc($1::text, created_at)::timestamp AS bucket,
+       COUNT(1) AS visits,
+       COUNT(DISTINCT ip) AS unique_ips
+FROM link_visits
+WHERE link_id = $2
+  AND status = 302
+  AND is_bot = FALSE
+  AND created_at >= $3::timestamp
+  AND created_at < $4::timestamp
+GROUP BY bucket
+ORDER BY bucket
+`
+
+type GetLinkVisitsTimeSeriesParams struct {
+	BucketSize string
+	LinkID     int64
+	FromTime   time.Time
+	ToTime     time.Time
+}
+
+type GetLinkVisitsTimeSeriesRow struct {
+	Bucket    time.Time
+	Visits    int64
+	UniqueIps int64
+}
+
+func (q *Queries) GetLinkVisitsTimeSeries(ctx context.Context, arg GetLinkVisitsTimeSeriesParams) ([]GetLinkVisitsTimeSeriesRow, error) {
+	rows, err := q.db.QueryContext(ctx, getLinkVisitsTimeSeries,
+		arg.BucketSize,
+		arg.LinkID,
+		arg.FromTime,
+		arg.ToTime,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetLinkVisitsTimeSeriesRow
+	for rows.Next() {
+		var i GetLinkVisitsTimeSeriesRow
+		if err := rows.Scan(&i.Bucket, &i.Visits, &i.UniqueIps); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getLinkVisitByID = `-- name: GetLinkVisitByID :one
-SELECT id, link_id, ip, user_agent, referer, status, created_at
+SELECT id, link_id, ip, user_agent, referer, status, created_at,
+       browser, browser_version, os, device_type, is_bot,
+       country, region, city, asn, as_org
 FROM link_visits
 WHERE id = $1
 `
@@ -116,12 +543,24 @@ func (q *Queries) GetLinkVisitByID(ctx context.Context, id int64) (LinkVisit, er
 		&i.Referer,
 		&i.Status,
 		&i.CreatedAt,
+		&i.Browser,
+		&i.BrowserVersion,
+		&i.Os,
+		&i.DeviceType,
+		&i.IsBot,
+		&i.Country,
+		&i.Region,
+		&i.City,
+		&i.Asn,
+		&i.AsOrg,
 	)
 	return i, err
 }
 
 const getVisitsByLinkID = `-- name: GetVisitsByLinkID :many
-SELECT id, link_id, ip, user_agent, referer, status, created_at
+SELECT id, link_id, ip, user_agent, referer, status, created_at,
+       browser, browser_version, os, device_type, is_bot,
+       country, region, city, asn, as_org
 FROM link_visits
 WHERE link_id = $1
 ORDER BY created_at DESC
@@ -144,6 +583,16 @@ func (q *Queries) GetVisitsByLinkID(ctx context.Context, linkID int64) ([]LinkVi
 			&i.Referer,
 			&i.Status,
 			&i.CreatedAt,
+			&i.Browser,
+			&i.BrowserVersion,
+			&i.Os,
+			&i.DeviceType,
+			&i.IsBot,
+			&i.Country,
+			&i.Region,
+			&i.City,
+			&i.Asn,
+			&i.AsOrg,
 		); err != nil {
 			return nil, err
 		}
@@ -8,24 +8,58 @@ package sqlcdb
 import (
 	"context"
 	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
 )
 
+const claimRedirect = `-- name: ClaimRedirect :execrows
+UPDATE links
+    SET redirects = redirects + 1,
+        expired = redirects + 1 >= max_visits
+WHERE id = $1
+  AND max_visits IS NOT NULL
+  AND redirects < max_visits
+  AND expired = FALSE
+`
+
+func (q *Queries) ClaimRedirect(ctx context.Context, id int64) (int64, error) {
+	result, err := q.db.ExecContext(ctx, claimRedirect, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 const createLink = `-- name: CreateLink :one
 INSERT INTO links (
-    original_url, short_name
+    original_url, short_name, expires_at, max_visits, password_hash, owner_id, domain_id
 ) VALUES (
-    $1, $2
+    $1, $2, $3, $4, $5, $6, $7
 )
-RETURNING id, original_url, short_name, created_at, updated_at
+RETURNING id, original_url, short_name, created_at, updated_at, expires_at, max_visits, expired, password_hash, owner_id, domain_id, redirects
 `
 
 type CreateLinkParams struct {
-	OriginalUrl sql.NullString
-	ShortName   sql.NullString
+	OriginalUrl  string
+	ShortName    string
+	ExpiresAt    sql.NullTime
+	MaxVisits    sql.NullInt64
+	PasswordHash sql.NullString
+	OwnerID      sql.NullInt64
+	DomainID     sql.NullInt64
 }
 
 func (q *Queries) CreateLink(ctx context.Context, arg CreateLinkParams) (Link, error) {
-	row := q.db.QueryRowContext(ctx, createLink, arg.OriginalUrl, arg.ShortName)
+	row := q.db.QueryRowContext(ctx, createLink,
+		arg.OriginalUrl,
+		arg.ShortName,
+		arg.ExpiresAt,
+		arg.MaxVisits,
+		arg.PasswordHash,
+		arg.OwnerID,
+		arg.DomainID,
+	)
 	var i Link
 	err := row.Scan(
 		&i.ID,
@@ -33,27 +67,118 @@ func (q *Queries) CreateLink(ctx context.Context, arg CreateLinkParams) (Link, e
 		&i.ShortName,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.ExpiresAt,
+		&i.MaxVisits,
+		&i.Expired,
+		&i.PasswordHash,
+		&i.OwnerID,
+		&i.DomainID,
+		&i.Redirects,
 	)
 	return i, err
 }
 
 const deleteLink = `-- name: DeleteLink :exec
 DELETE FROM links
-WHERE id = $1
+WHERE id = $1 AND owner_id = $2
 `
 
-func (q *Queries) DeleteLink(ctx context.Context, id int64) error {
-	_, err := q.db.ExecContext(ctx, deleteLink, id)
+type DeleteLinkParams struct {
+	ID      int64
+	OwnerID sql.NullInt64
+}
+
+func (q *Queries) DeleteLink(ctx context.Context, arg DeleteLinkParams) error {
+	_, err := q.db.ExecContext(ctx, deleteLink, arg.ID, arg.OwnerID)
 	return err
 }
 
+const deleteLinks = `-- name: DeleteLinks :many
+DELETE FROM links
+WHERE owner_id = $1 AND id = ANY($2::bigint[])
+RETURNING id, short_name, domain_id
+`
+
+type DeleteLinksParams struct {
+	OwnerID sql.NullInt64
+	Ids     []int64
+}
+
+type DeleteLinksRow struct {
+	ID        int64
+	ShortName string
+	DomainID  sql.NullInt64
+}
+
+func (q *Queries) DeleteLinks(ctx context.Context, arg DeleteLinksParams) ([]DeleteLinksRow, error) {
+	rows, err := q.db.QueryContext(ctx, deleteLinks, arg.OwnerID, pq.Array(arg.Ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []DeleteLinksRow
+	for rows.Next() {
+		var i DeleteLinksRow
+		if err := rows.Scan(&i.ID, &i.ShortName, &i.DomainID); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getExistingShortNames = `-- name: GetExistingShortNames :many
+SELECT short_name FROM links
+WHERE LOWER(short_name) = ANY($1::text[])
+  AND COALESCE(domain_id, 0) = COALESCE($2::bigint, 0)
+`
+
+type GetExistingShortNamesParams struct {
+	ShortNames []string
+	DomainID   sql.NullInt64
+}
+
+func (q *Queries) GetExistingShortNames(ctx context.Context, arg GetExistingShortNamesParams) ([]string, error) {
+	rows, err := q.db.QueryContext(ctx, getExistingShortNames, pq.Array(arg.ShortNames), arg.DomainID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var short_name string
+		if err := rows.Scan(&short_name); err != nil {
+			return nil, err
+		}
+		items = append(items, short_name)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getLink = `-- name: GetLink :one
-SELECT id, original_url, short_name, created_at, updated_at FROM links
-WHERE id = $1 LIMIT 1
+SELECT id, original_url, short_name, created_at, updated_at, expires_at, max_visits, expired, password_hash, owner_id, domain_id, redirects FROM links
+WHERE id = $1 AND owner_id = $2 LIMIT 1
 `
 
-func (q *Queries) GetLink(ctx context.Context, id int64) (Link, error) {
-	row := q.db.QueryRowContext(ctx, getLink, id)
+type GetLinkParams struct {
+	ID      int64
+	OwnerID sql.NullInt64
+}
+
+func (q *Queries) GetLink(ctx context.Context, arg GetLinkParams) (Link, error) {
+	row := q.db.QueryRowContext(ctx, getLink, arg.ID, arg.OwnerID)
 	var i Link
 	err := row.Scan(
 		&i.ID,
@@ -61,18 +186,30 @@ func (q *Queries) GetLink(ctx context.Context, id int64) (Link, error) {
 		&i.ShortName,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.ExpiresAt,
+		&i.MaxVisits,
+		&i.Expired,
+		&i.PasswordHash,
+		&i.OwnerID,
+		&i.DomainID,
+		&i.Redirects,
 	)
 	return i, err
 }
 
 const getLinkByShortName = `-- name: GetLinkByShortName :one
-SELECT id, original_url, short_name, created_at, updated_at FROM links
-WHERE short_name = $1
-LIMIT 1
+SELECT id, original_url, short_name, created_at, updated_at, expires_at, max_visits, expired, password_hash, owner_id, domain_id, redirects FROM links
+WHERE LOWER(short_name) = LOWER($1::text)
+  AND COALESCE(domain_id, 0) = COALESCE($2::bigint, 0)
 `
 
-func (q *Queries) GetLinkByShortName(ctx context.Context, shortName sql.NullString) (Link, error) {
-	row := q.db.QueryRowContext(ctx, getLinkByShortName, shortName)
+type GetLinkByShortNameParams struct {
+	ShortName string
+	DomainID  sql.NullInt64
+}
+
+func (q *Queries) GetLinkByShortName(ctx context.Context, arg GetLinkByShortNameParams) (Link, error) {
+	row := q.db.QueryRowContext(ctx, getLinkByShortName, arg.ShortName, arg.DomainID)
 	var i Link
 	err := row.Scan(
 		&i.ID,
@@ -80,24 +217,158 @@ func (q *Queries) GetLinkByShortName(ctx context.Context, shortName sql.NullStri
 		&i.ShortName,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.ExpiresAt,
+		&i.MaxVisits,
+		&i.Expired,
+		&i.PasswordHash,
+		&i.OwnerID,
+		&i.DomainID,
+		&i.Redirects,
 	)
 	return i, err
 }
 
-const getLinks = `-- name: GetLinks :many
-SELECT id, original_url, short_name, created_at, updated_at FROM links
+const getLinksForExport = `-- name: GetLinksForExport :many
+SELECT links.id, links.original_url, links.short_name, links.created_at,
+       links.expires_at, links.max_visits, links.domain_id,
+       (SELECT COUNT(1) FROM link_visits WHERE link_visits.link_id = links.id) AS visits
+FROM links
+WHERE links.owner_id = $1 AND links.id > $2
+ORDER BY links.id
+LIMIT $3
+`
+
+type GetLinksForExportParams struct {
+	OwnerID sql.NullInt64
+	ID      int64
+	Limit   int32
+}
+
+type GetLinksForExportRow struct {
+	ID          int64
+	OriginalUrl string
+	ShortName   string
+	CreatedAt   time.Time
+	ExpiresAt   sql.NullTime
+	MaxVisits   sql.NullInt64
+	DomainID    sql.NullInt64
+	Visits      int64
+}
+
+func (q *Queries) GetLinksForExport(ctx context.Context, arg GetLinksForExportParams) ([]GetLinksForExportRow, error) {
+	rows, err := q.db.QueryContext(ctx, getLinksForExport, arg.OwnerID, arg.ID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetLinksForExportRow
+	for rows.Next() {
+		var i GetLinksForExportRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.OriginalUrl,
+			&i.ShortName,
+			&i.CreatedAt,
+			&i.ExpiresAt,
+			&i.MaxVisits,
+			&i.DomainID,
+			&i.Visits,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getLinksAfter = `-- name: GetLinksAfter :many
+SELECT id, original_url, short_name, created_at, updated_at, expires_at, max_visits, expired, password_hash, owner_id, domain_id, redirects FROM links
+WHERE owner_id = $1
+  AND id > $2
+  AND ($3::boolean IS NULL OR EXISTS (
+    SELECT 1 FROM link_health
+    WHERE link_health.link_id = links.id
+      AND link_health.broken = $3::boolean
+  ))
+  AND ($4::text IS NULL
+    OR original_url ILIKE $4::text
+    OR short_name ILIKE $4::text)
+  AND ($5::bigint[] IS NULL OR id = ANY($5::bigint[]))
+  AND ($6::timestamp IS NULL OR created_at >= $6::timestamp)
+  AND ($7::timestamp IS NULL OR created_at < $7::timestamp)
 ORDER BY id
-LIMIT $1
-OFFSET $2
+LIMIT $8
 `
 
-type GetLinksParams struct {
-	Limit  int32
-	Offset int32
+type GetLinksAfterParams struct {
+	OwnerID     sql.NullInt64
+	AfterID     int64
+	Broken      sql.NullBool
+	Q           sql.NullString
+	Ids         []int64
+	CreatedFrom sql.NullTime
+	CreatedTo   sql.NullTime
+	Limit       int32
+}
+
+func (q *Queries) GetLinksAfter(ctx context.Context, arg GetLinksAfterParams) ([]Link, error) {
+	rows, err := q.db.QueryContext(ctx, getLinksAfter,
+		arg.OwnerID,
+		arg.AfterID,
+		arg.Broken,
+		arg.Q,
+		pq.Array(arg.Ids),
+		arg.CreatedFrom,
+		arg.CreatedTo,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Link
+	for rows.Next() {
+		var i Link
+		if err := rows.Scan(
+			&i.ID,
+			&i.OriginalUrl,
+			&i.ShortName,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.ExpiresAt,
+			&i.MaxVisits,
+			&i.Expired,
+			&i.PasswordHash,
+			&i.OwnerID,
+			&i.DomainID,
+			&i.Redirects,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
 }
 
-func (q *Queries) GetLinks(ctx context.Context, arg GetLinksParams) ([]Link, error) {
-	rows, err := q.db.QueryContext(ctx, getLinks, arg.Limit, arg.Offset)
+const getLinksByIDs = `-- name: GetLinksByIDs :many
+SELECT id, original_url, short_name, created_at, updated_at, expires_at, max_visits, expired, password_hash, owner_id, domain_id, redirects FROM links
+WHERE id = ANY($1::bigint[])
+`
+
+func (q *Queries) GetLinksByIDs(ctx context.Context, ids []int64) ([]Link, error) {
+	rows, err := q.db.QueryContext(ctx, getLinksByIDs, pq.Array(ids))
 	if err != nil {
 		return nil, err
 	}
@@ -111,6 +382,13 @@ func (q *Queries) GetLinks(ctx context.Context, arg GetLinksParams) ([]Link, err
 			&i.ShortName,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.ExpiresAt,
+			&i.MaxVisits,
+			&i.Expired,
+			&i.PasswordHash,
+			&i.OwnerID,
+			&i.DomainID,
+			&i.Redirects,
 		); err != nil {
 			return nil, err
 		}
@@ -127,31 +405,120 @@ func (q *Queries) GetLinks(ctx context.Context, arg GetLinksParams) ([]Link, err
 
 const getLinksCount = `-- name: GetLinksCount :one
 SELECT COUNT(1) FROM links
+WHERE owner_id = $1
+  AND ($2::boolean IS NULL OR EXISTS (
+    SELECT 1 FROM link_health
+    WHERE link_health.link_id = links.id
+      AND link_health.broken = $2::boolean
+  ))
+  AND ($3::text IS NULL
+    OR original_url ILIKE $3::text
+    OR short_name ILIKE $3::text)
+  AND ($4::bigint[] IS NULL OR id = ANY($4::bigint[]))
+  AND ($5::timestamp IS NULL OR created_at >= $5::timestamp)
+  AND ($6::timestamp IS NULL OR created_at < $6::timestamp)
 `
 
-func (q *Queries) GetLinksCount(ctx context.Context) (int64, error) {
-	row := q.db.QueryRowContext(ctx, getLinksCount)
+type GetLinksCountParams struct {
+	OwnerID     sql.NullInt64
+	Broken      sql.NullBool
+	Q           sql.NullString
+	Ids         []int64
+	CreatedFrom sql.NullTime
+	CreatedTo   sql.NullTime
+}
+
+func (q *Queries) GetLinksCount(ctx context.Context, arg GetLinksCountParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, getLinksCount,
+		arg.OwnerID,
+		arg.Broken,
+		arg.Q,
+		pq.Array(arg.Ids),
+		arg.CreatedFrom,
+		arg.CreatedTo,
+	)
 	var count int64
 	err := row.Scan(&count)
 	return count, err
 }
 
+const markExpiredLinks = `-- name: MarkExpiredLinks :execrows
+UPDATE links
+    SET expired = TRUE
+WHERE expired = FALSE
+  AND (
+    (expires_at IS NOT NULL AND expires_at <= NOW())
+    OR (max_visits IS NOT NULL AND max_visits <= redirects)
+  )
+`
+
+func (q *Queries) MarkExpiredLinks(ctx context.Context) (int64, error) {
+	result, err := q.db.ExecContext(ctx, markExpiredLinks)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const nextShortCodeSequence = `-- name: NextShortCodeSequence :one
+SELECT nextval('short_code_seq')::bigint
+`
+
+func (q *Queries) NextShortCodeSequence(ctx context.Context) (int64, error) {
+	row := q.db.QueryRowContext(ctx, nextShortCodeSequence)
+	var column_1 int64
+	err := row.Scan(&column_1)
+	return column_1, err
+}
+
 const updateLink = `-- name: UpdateLink :one
 UPDATE links
-    SET original_url = $2,
-        short_name = $3
-WHERE id = $1
-RETURNING id, original_url, short_name, created_at, updated_at
+    SET original_url = $1,
+        short_name = $2,
+        domain_id = $3,
+        expires_at = $4,
+        max_visits = $5,
+        -- The counter is only kept while a limit is set.
+        redirects = CASE WHEN links.max_visits IS NULL AND $5::bigint IS NOT NULL
+            THEN (
+                SELECT COUNT(1) FROM link_visits
+                WHERE link_visits.link_id = links.id
+                  AND link_visits.status = 302
+                  AND link_visits.is_bot = FALSE
+            )
+            ELSE redirects END,
+        expired = FALSE,
+        password_hash = CASE WHEN $6::boolean
+            THEN $7::text
+            ELSE password_hash END
+WHERE id = $8 AND owner_id = $9
+RETURNING id, original_url, short_name, created_at, updated_at, expires_at, max_visits, expired, password_hash, owner_id, domain_id, redirects
 `
 
 type UpdateLinkParams struct {
-	ID          int64
-	OriginalUrl sql.NullString
-	ShortName   sql.NullString
+	OriginalUrl  string
+	ShortName    string
+	DomainID     sql.NullInt64
+	ExpiresAt    sql.NullTime
+	MaxVisits    sql.NullInt64
+	SetPassword  bool
+	PasswordHash sql.NullString
+	ID           int64
+	OwnerID      sql.NullInt64
 }
 
 func (q *Queries) UpdateLink(ctx context.Context, arg UpdateLinkParams) (Link, error) {
-	row := q.db.QueryRowContext(ctx, updateLink, arg.ID, arg.OriginalUrl, arg.ShortName)
+	row := q.db.QueryRowContext(ctx, updateLink,
+		arg.OriginalUrl,
+		arg.ShortName,
+		arg.DomainID,
+		arg.ExpiresAt,
+		arg.MaxVisits,
+		arg.SetPassword,
+		arg.PasswordHash,
+		arg.ID,
+		arg.OwnerID,
+	)
 	var i Link
 	err := row.Scan(
 		&i.ID,
@@ -159,6 +526,13 @@ func (q *Queries) UpdateLink(ctx context.Context, arg UpdateLinkParams) (Link, e
 		&i.ShortName,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.ExpiresAt,
+		&i.MaxVisits,
+		&i.Expired,
+		&i.PasswordHash,
+		&i.OwnerID,
+		&i.DomainID,
+		&i.Redirects,
 	)
 	return i, err
 }
@@ -6,22 +6,71 @@ package sqlcdb
 
 import (
 	"database/sql"
+	"time"
 )
 
+type ApiKey struct {
+	ID         int64
+	UserID     int64
+	KeyHash    string
+	CreatedAt  sql.NullTime
+	LastUsedAt sql.NullTime
+	RevokedAt  sql.NullTime
+}
+
+type Domain struct {
+	ID        int64
+	Host      string
+	OwnerID   int64
+	CreatedAt sql.NullTime
+}
+
 type Link struct {
-	ID          int64
-	OriginalUrl sql.NullString
-	ShortName   sql.NullString
-	CreatedAt   sql.NullTime
-	UpdatedAt   sql.NullTime
+	ID           int64
+	OriginalUrl  string
+	ShortName    string
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	ExpiresAt    sql.NullTime
+	MaxVisits    sql.NullInt64
+	Expired      bool
+	PasswordHash sql.NullString
+	OwnerID      sql.NullInt64
+	DomainID     sql.NullInt64
+	Redirects    int64
+}
+
+type LinkHealth struct {
+	LinkID     int64
+	StatusCode sql.NullInt32
+	Error      sql.NullString
+	LatencyMs  int32
+	Broken     bool
+	CheckedAt  time.Time
 }
 
 type LinkVisit struct {
+	ID             int64
+	LinkID         int64
+	Ip             string
+	UserAgent      sql.NullString
+	Referer        sql.NullString
+	Status         int32
+	CreatedAt      sql.NullTime
+	Browser        sql.NullString
+	BrowserVersion sql.NullString
+	Os             sql.NullString
+	DeviceType     sql.NullString
+	IsBot          bool
+	Country        sql.NullString
+	Region         sql.NullString
+	City           sql.NullString
+	Asn            sql.NullInt64
+	AsOrg          sql.NullString
+}
+
+type User struct {
 	ID        int64
-	LinkID    int64
-	Ip        string
-	UserAgent sql.NullString
-	Referer   sql.NullString
-	Status    int32
+	Email     string
 	CreatedAt sql.NullTime
 }
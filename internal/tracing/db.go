@@ -0,0 +1,79 @@
+package tracing
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"strings"
+
+	"github.com/XSAM/otelsql"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Open opens a database whose driver is wrapped so that every statement,
+// including those in transactions and COPY, gets a client span. Statements
+// of sqlc queries are named after the query. Statements only get a span
+// when ctx already carries one, so background work does not produce a root
+// span per query.
+func Open(driverName, dataSourceName string) (*sql.DB, error) {
+	return otelsql.Open(driverName, dataSourceName,
+		otelsql.WithAttributes(semconv.DBSystemNamePostgreSQL),
+		otelsql.WithSpanNameFormatter(spanName),
+		otelsql.WithAttributesGetter(queryAttributes),
+		otelsql.WithSpanOptions(otelsql.SpanOptions{
+			// queryAttributes sets db.query.text instead of the older
+			// db.statement.
+			DisableQuery:   true,
+			DisableErrSkip: true,
+			// Query spans only cover the query itself, not reading the rows.
+			OmitRows:             true,
+			OmitConnResetSession: true,
+			SpanFilter:           filterSpan,
+		}),
+	)
+}
+
+func filterSpan(ctx context.Context, method otelsql.Method, query string, args []driver.NamedValue) bool {
+	if !trace.SpanFromContext(ctx).SpanContext().IsValid() {
+		return false
+	}
+	// Every row sent with COPY is an Exec of the prepared statement; only
+	// the final Exec without arguments, which writes the rows, gets a span.
+	if method == otelsql.MethodStmtExec && len(args) > 0 && strings.HasPrefix(query, "COPY ") {
+		return false
+	}
+	return true
+}
+
+// spanName names statements of sqlc queries after the query, and everything
+// else, such as commits, after the database/sql method.
+func spanName(_ context.Context, method otelsql.Method, query string) string {
+	if name, ok := queryName(query); ok {
+		return name
+	}
+	return string(method)
+}
+
+func queryAttributes(_ context.Context, _ otelsql.Method, query string, _ []driver.NamedValue) []attribute.KeyValue {
+	if query == "" {
+		return nil
+	}
+	attrs := []attribute.KeyValue{attribute.String("db.query.text", query)}
+	if name, ok := queryName(query); ok {
+		attrs = append(attrs, semconv.DBQuerySummary(name))
+	}
+	return attrs
+}
+
+// queryName extracts the query name from the "-- name: GetLink :one"
+// header sqlc puts in front of every statement.
+func queryName(query string) (string, bool) {
+	if rest, ok := strings.CutPrefix(query, "-- name: "); ok {
+		if name, _, ok := strings.Cut(rest, " "); ok {
+			return name, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,161 @@
+package tracing
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"slices"
+	"testing"
+
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// stubDriver accepts every statement without doing anything.
+type stubDriver struct{}
+
+func (stubDriver) Open(string) (driver.Conn, error) { return stubConn{}, nil }
+
+type stubConn struct{}
+
+func (stubConn) Prepare(string) (driver.Stmt, error) { return stubStmt{}, nil }
+func (stubConn) Close() error                        { return nil }
+func (stubConn) Begin() (driver.Tx, error)           { return stubTx{}, nil }
+
+type stubStmt struct{}
+
+func (stubStmt) Close() error                               { return nil }
+func (stubStmt) NumInput() int                              { return -1 }
+func (stubStmt) Exec([]driver.Value) (driver.Result, error) { return driver.RowsAffected(1), nil }
+func (stubStmt) Query([]driver.Value) (driver.Rows, error)  { return stubRows{}, nil }
+
+type stubTx struct{}
+
+func (stubTx) Commit() error   { return nil }
+func (stubTx) Rollback() error { return nil }
+
+type stubRows struct{}
+
+func (stubRows) Columns() []string         { return nil }
+func (stubRows) Close() error              { return nil }
+func (stubRows) Next([]driver.Value) error { return io.EOF }
+
+func init() {
+	sql.Register("tracing-stub", stubDriver{})
+}
+
+func openTestDB(t *testing.T) (*sql.DB, *tracetest.InMemoryExporter) {
+	t.Helper()
+
+	exporter := newTestProvider(t)
+	db, err := Open("tracing-stub", "")
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	return db, exporter
+}
+
+// childSpans returns the names of the spans recorded under parent.
+func childSpans(exporter *tracetest.InMemoryExporter, parent string) []string {
+	var parentID string
+	for _, span := range exporter.GetSpans() {
+		if span.Name == parent {
+			parentID = span.SpanContext.SpanID().String()
+		}
+	}
+
+	var names []string
+	for _, span := range exporter.GetSpans() {
+		if span.Parent.SpanID().String() == parentID {
+			names = append(names, span.Name)
+		}
+	}
+	return names
+}
+
+func TestOpenTracesTransactions(t *testing.T) {
+	db, exporter := openTestDB(t)
+
+	ctx, span := Start(context.Background(), "request")
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatalf("BeginTx: %v", err)
+	}
+	if _, err := tx.ExecContext(ctx, "-- name: InsertLink :one\nINSERT INTO links DEFAULT VALUES"); err != nil {
+		t.Fatalf("ExecContext: %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+	span.End()
+
+	got := childSpans(exporter, "request")
+	for _, want := range []string{"sql.conn.begin_tx", "InsertLink", "sql.tx.commit"} {
+		if !slices.Contains(got, want) {
+			t.Errorf("spans under the request = %v, want %s among them", got, want)
+		}
+	}
+	for _, s := range exporter.GetSpans() {
+		if s.Name != "InsertLink" {
+			continue
+		}
+		for _, attr := range s.Attributes {
+			if attr.Key == "db.query.summary" && attr.Value.AsString() != "InsertLink" {
+				t.Errorf("db.query.summary = %q, want InsertLink", attr.Value.AsString())
+			}
+		}
+	}
+}
+
+func TestOpenTracesCopyOnce(t *testing.T) {
+	db, exporter := openTestDB(t)
+
+	ctx, span := Start(context.Background(), "flush")
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatalf("BeginTx: %v", err)
+	}
+	stmt, err := tx.PrepareContext(ctx, `COPY "link_visits" ("link_id") FROM STDIN`)
+	if err != nil {
+		t.Fatalf("PrepareContext: %v", err)
+	}
+	for id := range 3 {
+		if _, err := stmt.ExecContext(ctx, id); err != nil {
+			t.Fatalf("ExecContext: %v", err)
+		}
+	}
+	if _, err := stmt.ExecContext(ctx); err != nil {
+		t.Fatalf("ExecContext: %v", err)
+	}
+	_ = stmt.Close()
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+	span.End()
+
+	got := childSpans(exporter, "flush")
+	var execs int
+	for _, name := range got {
+		if name == "sql.stmt.exec" {
+			execs++
+		}
+	}
+	if execs != 1 {
+		t.Errorf("spans under the flush = %v, want one sql.stmt.exec", got)
+	}
+	if !slices.Contains(got, "sql.conn.prepare") {
+		t.Errorf("spans under the flush = %v, want sql.conn.prepare among them", got)
+	}
+}
+
+func TestOpenSkipsStatementsWithoutParent(t *testing.T) {
+	db, exporter := openTestDB(t)
+
+	if _, err := db.ExecContext(context.Background(), "-- name: DeleteLink :exec\nDELETE FROM links"); err != nil {
+		t.Fatalf("ExecContext: %v", err)
+	}
+	if spans := exporter.GetSpans(); len(spans) != 0 {
+		t.Errorf("recorded %d spans without a parent, want none", len(spans))
+	}
+}
@@ -0,0 +1,64 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// newTestProvider installs a global tracer provider that records every span
+// synchronously in the returned exporter, until the test ends.
+func newTestProvider(t *testing.T) *tracetest.InMemoryExporter {
+	t.Helper()
+
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithSyncer(exporter),
+		sdktrace.WithSampler(sdktrace.AlwaysSample()),
+	)
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previous)
+		_ = provider.Shutdown(context.Background())
+	})
+	return exporter
+}
+
+type expectedError struct{}
+
+func (expectedError) Error() string { return "expected" }
+
+func TestEndIgnoring(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		expected   []error
+		wantStatus codes.Code
+	}{
+		{name: "ok", wantStatus: codes.Unset},
+		{name: "error", err: errors.New("boom"), wantStatus: codes.Error},
+		{name: "expected error", err: expectedError{}, expected: []error{expectedError{}}, wantStatus: codes.Unset},
+		{name: "unexpected error", err: errors.New("boom"), expected: []error{expectedError{}}, wantStatus: codes.Error},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exporter := newTestProvider(t)
+			_, span := Start(context.Background(), "op")
+			EndIgnoring(span, tt.err, tt.expected...)
+
+			spans := exporter.GetSpans()
+			if len(spans) != 1 {
+				t.Fatalf("recorded %d spans, want 1", len(spans))
+			}
+			if got := spans[0].Status.Code; got != tt.wantStatus {
+				t.Errorf("status = %v, want %v", got, tt.wantStatus)
+			}
+		})
+	}
+}
@@ -0,0 +1,183 @@
+// Package urlpolicy decides whether a destination URL may be shortened.
+package urlpolicy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/netip"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"markoni23/url-shortener/internal/model"
+)
+
+// lookupTimeout bounds the DNS lookup used to detect hostnames that point
+// to internal addresses.
+const lookupTimeout = 2 * time.Second
+
+// Rules is the content of the policy file, e.g.
+//
+//	{"allow_domains": [], "deny_domains": ["evil.example", "bit.ly"]}
+//
+// A domain matches itself and all of its subdomains. When AllowDomains is
+// not empty, only destinations matching it are accepted.
+type Rules struct {
+	AllowDomains []string `json:"allow_domains"`
+	DenyDomains  []string `json:"deny_domains"`
+}
+
+// Policy checks destination URLs against the configured rules. Besides the
+// domain lists it always rejects non-http(s) schemes, credentials, private,
+// loopback and link-local targets, and URLs pointing back at the shortener
+// itself.
+type Policy struct {
+	rules     Rules
+	ownHosts  map[string]struct{}
+	isOwnHost func(ctx context.Context, host string) bool
+	lookupIPs func(ctx context.Context, host string) ([]netip.Addr, error)
+}
+
+// New builds a Policy. rulesPath may be empty, in which case no domain
+// lists are applied. basePath is the public URL of the shortener.
+func New(rulesPath, basePath string) (*Policy, error) {
+	p := &Policy{
+		ownHosts:  make(map[string]struct{}),
+		lookupIPs: lookupIPs,
+	}
+
+	if rulesPath != "" {
+		raw, err := os.ReadFile(rulesPath)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(raw, &p.rules); err != nil {
+			return nil, fmt.Errorf("parse %s: %w", rulesPath, err)
+		}
+		p.rules.AllowDomains = normalizeDomains(p.rules.AllowDomains)
+		p.rules.DenyDomains = normalizeDomains(p.rules.DenyDomains)
+	}
+
+	if u, err := url.Parse(basePath); err == nil && u.Hostname() != "" {
+		p.AddOwnHost(u.Hostname())
+	}
+
+	return p, nil
+}
+
+// AddOwnHost registers another hostname the shortener is served from.
+func (p *Policy) AddOwnHost(host string) {
+	p.ownHosts[normalizeHost(host)] = struct{}{}
+}
+
+// SetOwnHostLookup registers a function that reports additional hosts the
+// shortener is served from, such as custom domains.
+func (p *Policy) SetOwnHostLookup(fn func(ctx context.Context, host string) bool) {
+	p.isOwnHost = fn
+}
+
+// Check returns a *model.DisallowedURLError when rawURL must not be
+// shortened.
+func (p *Policy) Check(ctx context.Context, rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return &model.DisallowedURLError{Reason: "must be a valid URL"}
+	}
+
+	scheme := strings.ToLower(u.Scheme)
+	if scheme != "http" && scheme != "https" {
+		return &model.DisallowedURLError{Reason: "only http and https URLs are allowed"}
+	}
+
+	// Credentials make a URL such as https://bank.example@evil.example
+	// look like it leads somewhere else.
+	if u.User != nil {
+		return &model.DisallowedURLError{Reason: "must not contain credentials"}
+	}
+
+	host := normalizeHost(u.Hostname())
+	if host == "" {
+		return &model.DisallowedURLError{Reason: "must contain a host"}
+	}
+
+	if _, ok := p.ownHosts[host]; ok || (p.isOwnHost != nil && p.isOwnHost(ctx, host)) {
+		return &model.DisallowedURLError{Reason: "must not point to this URL shortener"}
+	}
+
+	if len(p.rules.AllowDomains) > 0 && !matchesDomain(host, p.rules.AllowDomains) {
+		return &model.DisallowedURLError{Reason: "domain is not allowed"}
+	}
+	if matchesDomain(host, p.rules.DenyDomains) {
+		return &model.DisallowedURLError{Reason: "domain is not allowed"}
+	}
+
+	if addr, err := netip.ParseAddr(host); err == nil {
+		if IsInternal(addr) {
+			return &model.DisallowedURLError{Reason: "must not point to a private network address"}
+		}
+		return nil
+	}
+
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return &model.DisallowedURLError{Reason: "must not point to a private network address"}
+	}
+
+	// A hostname that does not resolve right now is accepted; only names
+	// known to point inside the network are refused.
+	ctx, cancel := context.WithTimeout(ctx, lookupTimeout)
+	defer cancel()
+
+	addrs, err := p.lookupIPs(ctx, host)
+	if err != nil {
+		return nil
+	}
+	for _, addr := range addrs {
+		if IsInternal(addr) {
+			return &model.DisallowedURLError{Reason: "must not point to a private network address"}
+		}
+	}
+
+	return nil
+}
+
+// IsInternal reports whether addr belongs to a private, loopback,
+// link-local or unspecified range.
+func IsInternal(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return addr.IsPrivate() ||
+		addr.IsLoopback() ||
+		addr.IsLinkLocalUnicast() ||
+		addr.IsLinkLocalMulticast() ||
+		addr.IsInterfaceLocalMulticast() ||
+		addr.IsUnspecified()
+}
+
+func matchesDomain(host string, domains []string) bool {
+	for _, domain := range domains {
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+	}
+	return false
+}
+
+func normalizeHost(host string) string {
+	return strings.TrimSuffix(strings.ToLower(host), ".")
+}
+
+func normalizeDomains(domains []string) []string {
+	res := make([]string, 0, len(domains))
+	for _, domain := range domains {
+		if domain = normalizeHost(strings.TrimSpace(domain)); domain != "" {
+			res = append(res, domain)
+		}
+	}
+	return res
+}
+
+func lookupIPs(ctx context.Context, host string) ([]netip.Addr, error) {
+	return net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+}
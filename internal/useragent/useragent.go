@@ -0,0 +1,161 @@
+package useragent
+
+import (
+	"strings"
+)
+
+const (
+	DeviceDesktop = "desktop"
+	DeviceMobile  = "mobile"
+	DeviceTablet  = "tablet"
+	DeviceBot     = "bot"
+	DeviceOther   = "other"
+)
+
+type Info struct {
+	Browser        string
+	BrowserVersion string
+	OS             string
+	DeviceType     string
+	IsBot          bool
+}
+
+// knownBots maps a lower-case User-Agent fragment to the name reported as
+// the browser family. Link unfurlers come first because several of them
+// also contain generic "bot" tokens.
+var knownBots = []struct {
+	token string
+	name  string
+}{
+	{"slackbot", "Slackbot"},
+	{"twitterbot", "Twitterbot"},
+	{"facebookexternalhit", "Facebook"},
+	{"facebookcatalog", "Facebook"},
+	{"linkedinbot", "LinkedInBot"},
+	{"discordbot", "Discordbot"},
+	{"telegrambot", "TelegramBot"},
+	{"whatsapp", "WhatsApp"},
+	{"skypeuripreview", "Skype"},
+	{"embedly", "Embedly"},
+	{"pinterest", "Pinterest"},
+	{"googlebot", "Googlebot"},
+	{"bingbot", "Bingbot"},
+	{"bingpreview", "Bingbot"},
+	{"yandexbot", "YandexBot"},
+	{"duckduckbot", "DuckDuckBot"},
+	{"baiduspider", "Baiduspider"},
+	{"applebot", "Applebot"},
+	{"headlesschrome", "HeadlessChrome"},
+	{"curl/", "curl"},
+	{"wget/", "Wget"},
+	{"python-requests", "python-requests"},
+	{"go-http-client", "Go-http-client"},
+	{"okhttp", "okhttp"},
+	{"bot", "Other bot"},
+	{"crawler", "Other bot"},
+	{"spider", "Other bot"},
+	{"preview", "Other bot"},
+}
+
+// browsers is checked in order: Chromium-based browsers also announce
+// "Chrome/" and "Safari/", so the more specific tokens come first.
+var browsers = []struct {
+	token string
+	name  string
+}{
+	{"Edg/", "Edge"},
+	{"EdgA/", "Edge"},
+	{"EdgiOS/", "Edge"},
+	{"OPR/", "Opera"},
+	{"SamsungBrowser/", "Samsung Internet"},
+	{"YaBrowser/", "Yandex Browser"},
+	{"FxiOS/", "Firefox"},
+	{"Firefox/", "Firefox"},
+	{"CriOS/", "Chrome"},
+	{"Chrome/", "Chrome"},
+	{"Version/", "Safari"},
+	{"MSIE ", "Internet Explorer"},
+	{"Trident/", "Internet Explorer"},
+}
+
+func Parse(raw string) Info {
+	if raw == "" {
+		return Info{DeviceType: DeviceOther}
+	}
+
+	lower := strings.ToLower(raw)
+	for _, bot := range knownBots {
+		if strings.Contains(lower, bot.token) {
+			return Info{
+				Browser:    bot.name,
+				OS:         parseOS(raw),
+				DeviceType: DeviceBot,
+				IsBot:      true,
+			}
+		}
+	}
+
+	info := Info{OS: parseOS(raw)}
+	for _, browser := range browsers {
+		if i := strings.Index(raw, browser.token); i >= 0 {
+			info.Browser = browser.name
+			info.BrowserVersion = versionAt(raw[i+len(browser.token):])
+			break
+		}
+	}
+	if info.Browser == "Internet Explorer" && strings.Contains(raw, "Trident/") {
+		if i := strings.Index(raw, "rv:"); i >= 0 {
+			info.BrowserVersion = versionAt(raw[i+3:])
+		}
+	}
+
+	info.DeviceType = parseDevice(raw, info.OS)
+
+	return info
+}
+
+func parseOS(raw string) string {
+	switch {
+	case strings.Contains(raw, "iPhone"), strings.Contains(raw, "iPad"), strings.Contains(raw, "iPod"):
+		return "iOS"
+	case strings.Contains(raw, "Android"):
+		return "Android"
+	case strings.Contains(raw, "Windows Phone"):
+		return "Windows Phone"
+	case strings.Contains(raw, "Windows"):
+		return "Windows"
+	case strings.Contains(raw, "CrOS"):
+		return "ChromeOS"
+	case strings.Contains(raw, "Mac OS X"), strings.Contains(raw, "Macintosh"):
+		return "macOS"
+	case strings.Contains(raw, "Linux"), strings.Contains(raw, "X11"):
+		return "Linux"
+	default:
+		return ""
+	}
+}
+
+func parseDevice(raw, os string) string {
+	switch {
+	case strings.Contains(raw, "iPad"), strings.Contains(raw, "Tablet"):
+		return DeviceTablet
+	case os == "Android" && !strings.Contains(raw, "Mobile"):
+		return DeviceTablet
+	case strings.Contains(raw, "Mobi"), strings.Contains(raw, "iPhone"), strings.Contains(raw, "iPod"),
+		os == "Android", os == "Windows Phone":
+		return DeviceMobile
+	case os != "":
+		return DeviceDesktop
+	default:
+		return DeviceOther
+	}
+}
+
+// versionAt returns the leading dotted version number of s.
+func versionAt(s string) string {
+	end := 0
+	for end < len(s) && (s[end] == '.' || (s[end] >= '0' && s[end] <= '9')) {
+		end++
+	}
+	return strings.TrimRight(s[:end], ".")
+}
@@ -0,0 +1,74 @@
+package useragent
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+		want Info
+	}{
+		{
+			name: "googlebot",
+			raw:  "Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)",
+			want: Info{Browser: "Googlebot", DeviceType: DeviceBot, IsBot: true},
+		},
+		{
+			name: "slack unfurler",
+			raw:  "Slackbot-LinkExpanding 1.0 (+https://api.slack.com/robots)",
+			want: Info{Browser: "Slackbot", DeviceType: DeviceBot, IsBot: true},
+		},
+		{
+			name: "curl",
+			raw:  "curl/8.5.0",
+			want: Info{Browser: "curl", DeviceType: DeviceBot, IsBot: true},
+		},
+		{
+			name: "chrome on android",
+			raw:  "Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.6367.82 Mobile Safari/537.36",
+			want: Info{Browser: "Chrome", BrowserVersion: "124.0.6367.82", OS: "Android", DeviceType: DeviceMobile},
+		},
+		{
+			name: "chrome on android tablet",
+			raw:  "Mozilla/5.0 (Linux; Android 13; SM-X700) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.6367.82 Safari/537.36",
+			want: Info{Browser: "Chrome", BrowserVersion: "124.0.6367.82", OS: "Android", DeviceType: DeviceTablet},
+		},
+		{
+			name: "safari on ios",
+			raw:  "Mozilla/5.0 (iPhone; CPU iPhone OS 17_4 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.4 Mobile/15E148 Safari/604.1",
+			want: Info{Browser: "Safari", BrowserVersion: "17.4", OS: "iOS", DeviceType: DeviceMobile},
+		},
+		{
+			name: "safari on ipad",
+			raw:  "Mozilla/5.0 (iPad; CPU OS 17_4 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.4 Mobile/15E148 Safari/604.1",
+			want: Info{Browser: "Safari", BrowserVersion: "17.4", OS: "iOS", DeviceType: DeviceTablet},
+		},
+		{
+			name: "edge on windows",
+			raw:  "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36 Edg/124.0.2478.67",
+			want: Info{Browser: "Edge", BrowserVersion: "124.0.2478.67", OS: "Windows", DeviceType: DeviceDesktop},
+		},
+		{
+			name: "firefox on macos",
+			raw:  "Mozilla/5.0 (Macintosh; Intel Mac OS X 14.4; rv:125.0) Gecko/20100101 Firefox/125.0",
+			want: Info{Browser: "Firefox", BrowserVersion: "125.0", OS: "macOS", DeviceType: DeviceDesktop},
+		},
+		{
+			name: "empty",
+			raw:  "",
+			want: Info{DeviceType: DeviceOther},
+		},
+		{
+			name: "unknown",
+			raw:  "SomeClient",
+			want: Info{DeviceType: DeviceOther},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Parse(tt.raw); got != tt.want {
+				t.Errorf("Parse(%q) = %+v, want %+v", tt.raw, got, tt.want)
+			}
+		})
+	}
+}
@@ -2,6 +2,7 @@ package utils
 
 import (
 	"fmt"
+	"reflect"
 	"strings"
 
 	"github.com/go-playground/validator/v10"
@@ -27,6 +28,12 @@ func FormatValidationErrors(err error) map[string]string {
 				fieldName = "original_url"
 			case "shortname":
 				fieldName = "short_name"
+			case "expiresat":
+				fieldName = "expires_at"
+			case "maxvisits":
+				fieldName = "max_visits"
+			case "domainid":
+				fieldName = "domain_id"
 			}
 
 			errors[fieldName] = formatFieldError(fieldError)
@@ -43,11 +50,22 @@ func formatFieldError(fe validator.FieldError) string {
 	case "url":
 		return "must be a valid URL"
 	case "min":
+		if fe.Kind() != reflect.String {
+			return fmt.Sprintf("must be at least %s", fe.Param())
+		}
 		return fmt.Sprintf("must be at least %s characters", fe.Param())
 	case "max":
 		return fmt.Sprintf("must be at most %s characters", fe.Param())
 	case "alphanum":
 		return "must contain only alphanumeric characters"
+	case "fqdn":
+		return "must be a valid domain name"
+	case "notreserved":
+		return "is reserved"
+	case "bcryptlen":
+		return "must be at most 72 bytes"
+	case "oneof":
+		return fmt.Sprintf("must be one of: %s", fe.Param())
 	default:
 		return fmt.Sprintf("validation failed on '%s' tag", fe.Tag())
 	}
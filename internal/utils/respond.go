@@ -0,0 +1,20 @@
+package utils
+
+import (
+	"log/slog"
+
+	sentrygin "github.com/getsentry/sentry-go/gin"
+	"github.com/gin-gonic/gin"
+)
+
+// AbortWithError logs err together with the request ID, reports it to
+// Sentry and responds with message only, so that internal details do not
+// reach clients. The X-Request-ID response header lets them be correlated.
+func AbortWithError(ctx *gin.Context, status int, message string, err error) {
+	slog.ErrorContext(ctx.Request.Context(), message, "error", err, "status", status)
+	if hub := sentrygin.GetHubFromContext(ctx); hub != nil {
+		hub.CaptureException(err)
+	}
+	_ = ctx.Error(err)
+	ctx.AbortWithStatusJSON(status, SimpleErrorResponse{Error: message})
+}
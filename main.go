@@ -1,27 +1,95 @@
 package main
 
 import (
-	"log"
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
 
 	"markoni23/url-shortener/internal/app"
 	"markoni23/url-shortener/internal/config"
 	"markoni23/url-shortener/internal/db"
+	"markoni23/url-shortener/internal/logging"
+	authService "markoni23/url-shortener/internal/service/auth"
+	"markoni23/url-shortener/internal/sqlcdb"
+
+	"github.com/redis/go-redis/v9"
 )
 
 func main() {
+	// The log format and level come from the configuration, so what
+	// LoadEnv logs is held back until the logger is set up.
+	buffer := logging.NewBuffer()
+	slog.SetDefault(slog.New(buffer))
 	cfg := config.LoadEnv()
 
+	logger, err := logging.New(os.Stdout, cfg.Log.Format, cfg.Log.Level)
+	if err != nil {
+		logger = slog.New(slog.NewTextHandler(os.Stderr, nil))
+	}
+	slog.SetDefault(logger)
+	buffer.Replay(context.Background(), logger.Handler())
+	if err != nil {
+		fatal("failed to configure logging", err)
+	}
+
 	database, err := db.InitDB(cfg.Database.DatabaseUrl)
 	if err != nil {
-		log.Fatalf("failed to connect to database: %v", err)
+		fatal("failed to connect to database", err)
 	}
 	defer func() {
 		if err := database.Close(); err != nil {
-			log.Printf("Error closing database: %v", err)
+			slog.Error("failed to close database", "error", err)
 		}
 	}()
 
-	if err := app.Run(cfg, database); err != nil {
-		log.Fatal(err)
+	// `url-shortener create-api-key <email>` issues an API key and exits.
+	// `url-shortener assign-links <email>` gives the links that predate API
+	// keys, and so have no owner, to that user.
+	if len(os.Args) > 1 {
+		command := os.Args[1]
+		if len(os.Args) != 3 || (command != "create-api-key" && command != "assign-links") {
+			fmt.Fprintln(os.Stderr, "usage: url-shortener create-api-key|assign-links <email>")
+			os.Exit(2)
+		}
+
+		auth := authService.NewService(sqlcdb.New(database))
+		switch command {
+		case "create-api-key":
+			key, err := auth.CreateAPIKey(context.Background(), os.Args[2])
+			if err != nil {
+				fatal("failed to create api key", err)
+			}
+			fmt.Println(key)
+		case "assign-links":
+			n, err := auth.AssignUnownedLinks(context.Background(), os.Args[2])
+			if err != nil {
+				fatal("failed to assign links", err)
+			}
+			fmt.Printf("assigned %d links to %s\n", n, os.Args[2])
+		}
+		return
+	}
+
+	var redisClient *redis.Client
+	if cfg.Redis.URL != "" {
+		redisClient, err = db.InitRedis(cfg.Redis.URL)
+		if err != nil {
+			fatal("failed to connect to redis", err)
+		}
+		defer func() {
+			if err := redisClient.Close(); err != nil {
+				slog.Error("failed to close redis", "error", err)
+			}
+		}()
 	}
+
+	if err := app.Run(cfg, database, redisClient); err != nil {
+		fatal("server stopped", err)
+	}
+}
+
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
 }